@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Signing algorithms for access tokens.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// ErrNoSigningKey is returned when the keyring has no key that may sign now.
+var ErrNoSigningKey = errors.New("auth: no active signing key")
+
+// Key is one access token signing key. HMAC keys have no id; asymmetric keys
+// are identified by the "kid" header of the tokens they sign.
+type Key struct {
+	ID  string
+	Alg string
+	// ActivatesAt is when the key starts signing. Keys are published in the
+	// JWKS before that so verifiers can cache them in time.
+	ActivatesAt time.Time
+	// ExpiresAt is when tokens signed with the key stop being accepted; nil
+	// until a newer key replaces it.
+	ExpiresAt *time.Time
+
+	private interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey
+	public  interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// NewHMACKey wraps the shared secret used with HS256.
+func NewHMACKey(secret []byte) Key {
+	return Key{Alg: AlgHS256, private: secret, public: secret}
+}
+
+// GenerateKey creates a new RS256 or EdDSA key with a random id.
+func GenerateKey(alg string, activatesAt time.Time) (Key, error) {
+	k := Key{Alg: alg, ActivatesAt: activatesAt}
+	switch alg {
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return k, err
+		}
+		k.private, k.public = priv, &priv.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return k, err
+		}
+		k.private, k.public = priv, pub
+	default:
+		return k, fmt.Errorf("auth: cannot generate %s keys", alg)
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return k, err
+	}
+	k.ID = base64.RawURLEncoding.EncodeToString(id)
+	return k, nil
+}
+
+// ActiveAt reports whether tokens signed with the key are accepted at t.
+func (k Key) ActiveAt(t time.Time) bool {
+	return k.ExpiresAt == nil || t.Before(*k.ExpiresAt)
+}
+
+// sealKey derives the AES key that protects stored private keys.
+func sealKey(secret []byte) ([]byte, error) {
+	return hkdf.Key(sha256.New, secret, nil, "ec-space signing key v1", 32)
+}
+
+// SealPrivateKey returns the private key PKCS#8-encoded and encrypted with
+// AES-256-GCM under a key derived from secret, ready to store.
+func (k Key) SealPrivateKey(secret []byte) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, der, []byte(k.ID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenKey rebuilds a stored key. It fails if secret is not the one the key
+// was sealed with or the stored algorithm doesn't match the key type.
+func OpenKey(id, alg, sealed string, secret []byte, activatesAt time.Time, expiresAt *time.Time) (Key, error) {
+	k := Key{ID: id, Alg: alg, ActivatesAt: activatesAt, ExpiresAt: expiresAt}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return k, err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return k, err
+	}
+	if len(raw) < aead.NonceSize() {
+		return k, errors.New("auth: sealed key is truncated")
+	}
+	der, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return k, fmt.Errorf("auth: cannot decrypt key %s (was jwt_secret changed?)", id)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return k, err
+	}
+	switch p := priv.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			return k, fmt.Errorf("auth: key %s is RSA but stored as %s", id, alg)
+		}
+		k.private, k.public = p, &p.PublicKey
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			return k, fmt.Errorf("auth: key %s is Ed25519 but stored as %s", id, alg)
+		}
+		k.private, k.public = p, p.Public()
+	default:
+		return k, fmt.Errorf("auth: key %s has unsupported type %T", id, priv)
+	}
+	return k, nil
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	key, err := sealKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Keyring holds the keys that sign and verify access tokens. It is safe for
+// concurrent use and can be swapped out while the server runs.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []Key // newest ActivatesAt first
+}
+
+// NewKeyring returns a keyring holding keys.
+func NewKeyring(keys ...Key) *Keyring {
+	r := &Keyring{}
+	r.Replace(keys)
+	return r
+}
+
+// Replace swaps in a new set of keys.
+func (r *Keyring) Replace(keys []Key) {
+	sorted := append([]Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ActivatesAt.After(sorted[j].ActivatesAt) })
+	r.mu.Lock()
+	r.keys = sorted
+	r.mu.Unlock()
+}
+
+// SigningKey returns the newest key that has activated and not expired.
+func (r *Keyring) SigningKey(now time.Time) (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if !k.ActivatesAt.After(now) && k.ActiveAt(now) {
+			return k, nil
+		}
+	}
+	return Key{}, ErrNoSigningKey
+}
+
+// verificationKey returns the key a token names. The token's alg must be the
+// algorithm the key was created for, so a public key can never be used as
+// an HMAC secret.
+func (r *Keyring) verificationKey(kid, alg string, now time.Time) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if k.ID == kid && k.Alg == alg && k.ActiveAt(now) {
+			return k.public, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// Algorithms lists the algorithms of the keys in the ring. Tokens with any
+// other alg are rejected before their signature is looked at.
+func (r *Keyring) Algorithms() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	algs := []string{}
+	seen := map[string]bool{}
+	for _, k := range r.keys {
+		if !seen[k.Alg] {
+			seen[k.Alg] = true
+			algs = append(algs, k.Alg)
+		}
+	}
+	return algs
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public half of every asymmetric key that is still
+// accepted, including keys that haven't started signing yet. HMAC secrets
+// are never published.
+func (r *Keyring) JWKS(now time.Time) json.RawMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := struct {
+		Keys []JWK `json:"keys"`
+	}{Keys: []JWK{}}
+	for _, k := range r.keys {
+		if !k.ActiveAt(now) {
+			continue
+		}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA", Kid: k.ID, Use: "sig", Alg: k.Alg,
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP", Kid: k.ID, Use: "sig", Alg: k.Alg, Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	raw, _ := json.Marshal(set)
+	return raw
+}
@@ -0,0 +1,116 @@
+// Package auth issues and verifies the tokens used by the API: short-lived
+// JWT access tokens signed by a Keyring (HS256, RS256 or EdDSA), opaque
+// rotating refresh tokens and the single-use tokens sent by email, plus the
+// personal API keys used by scripts.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for any access token that fails verification.
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Claims are the claims carried by an access token.
+type Claims struct {
+	UserID   uint   `json:"user_id"`
+	Role     string `json:"role"`
+	FamilyID string `json:"fid"`
+	// MFA is true when the login passed a second factor.
+	MFA bool `json:"mfa,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IssueAccessToken signs an access token for userID that belongs to the given
+// token family and expires after ttl, using the keyring's current key.
+func IssueAccessToken(keys *Keyring, userID uint, role, familyID string, mfa bool, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	key, err := keys.SigningKey(now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	exp := now.Add(ttl)
+	claims := Claims{
+		UserID:   userID,
+		Role:     role,
+		FamilyID: familyID,
+		MFA:      mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	signed, err := token.SignedString(key.private)
+	return signed, exp, err
+}
+
+// ParseAccessToken verifies tokenString and returns its claims. Only the
+// algorithms of the keys in the ring are accepted, and the kid must name a
+// key of exactly that algorithm. Tokens that were issued before token
+// families existed (no fid) are rejected.
+func ParseAccessToken(keys *Keyring, tokenString string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return keys.verificationKey(kid, t.Method.Alg(), time.Now())
+	}, jwt.WithValidMethods(keys.Algorithms()), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.UserID == 0 || claims.FamilyID == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// NewRefreshToken returns a random refresh token and the hash to store.
+func NewRefreshToken() (plain, hash string, err error) {
+	return NewOpaqueToken()
+}
+
+// NewOpaqueToken returns a random URL-safe token and the hash to store. It
+// backs every single-use token the API hands out (refresh, password reset,
+// email verification).
+func NewOpaqueToken() (plain, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain = base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashToken(plain), nil
+}
+
+// HashToken returns the SHA-256 hex digest under which a token is stored.
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix starts every API key, so AuthMiddleware can tell keys from
+// JWTs and leaked keys are easy to spot in code search.
+const APIKeyPrefix = "ecs_"
+
+// NewAPIKey returns a random API key, a short prefix that identifies it in
+// listings and the hash to store.
+func NewAPIKey() (plain, prefix, hash string, err error) {
+	token, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	plain = APIKeyPrefix + token
+	return plain, plain[:len(APIKeyPrefix)+8], HashToken(plain), nil
+}
+
+// IsAPIKey reports whether a bearer token looks like an API key.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
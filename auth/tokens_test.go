@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClaims() Claims {
+	now := time.Now()
+	return Claims{
+		UserID:   7,
+		Role:     "user",
+		FamilyID: "fam",
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+// signWith signs testClaims with any method and key, setting kid if given.
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustGenerate(t *testing.T, alg string, activatesAt time.Time) Key {
+	t.Helper()
+	k, err := GenerateKey(alg, activatesAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestParseAccessTokenAlgorithmPinning(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	rsaKey := mustGenerate(t, AlgRS256, past)
+	edKey := mustGenerate(t, AlgEdDSA, past.Add(-time.Hour))
+	keys := NewKeyring(rsaKey, edKey)
+
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(rsaKey.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger := mustGenerate(t, AlgRS256, past)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256 signed by the ring", signWith(t, jwt.SigningMethodRS256, rsaKey.ID, rsaKey.private), true},
+		{"EdDSA signed by the ring", signWith(t, jwt.SigningMethodEdDSA, edKey.ID, edKey.private), true},
+		{"alg none", signWith(t, jwt.SigningMethodNone, rsaKey.ID, jwt.UnsafeAllowNoneSignatureType), false},
+		{"alg none without kid", signWith(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType), false},
+		{"HS256 with the RSA public key as secret", signWith(t, jwt.SigningMethodHS256, rsaKey.ID, rsaPublicDER), false},
+		{"HS256 without kid", signWith(t, jwt.SigningMethodHS256, "", []byte("guess")), false},
+		{"RS512 with a ring key", signWith(t, jwt.SigningMethodRS512, rsaKey.ID, rsaKey.private), false},
+		{"kid of a key with another alg", signWith(t, jwt.SigningMethodEdDSA, rsaKey.ID, edKey.private), false},
+		{"unknown kid", signWith(t, jwt.SigningMethodRS256, stranger.ID, stranger.private), false},
+		{"missing kid", signWith(t, jwt.SigningMethodRS256, "", rsaKey.private), false},
+		{"ring kid, foreign signature", signWith(t, jwt.SigningMethodRS256, rsaKey.ID, stranger.private), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseAccessToken(keys, tt.token)
+			if tt.ok && (err != nil || claims.UserID != 7) {
+				t.Fatalf("ParseAccessToken() = %+v, %v; want the claims", claims, err)
+			}
+			if !tt.ok && err != ErrInvalidToken {
+				t.Fatalf("ParseAccessToken() err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	if got := keys.Algorithms(); len(got) != 2 || strings.Contains(strings.Join(got, ","), AlgHS256) {
+		t.Errorf("Algorithms() = %v, want only RS256 and EdDSA", got)
+	}
+}
+
+func TestParseAccessTokenRetiredKey(t *testing.T) {
+	now := time.Now()
+	stillPublished := now.Add(time.Hour)
+	gone := now.Add(-time.Minute)
+
+	retired := mustGenerate(t, AlgEdDSA, now.Add(-48*time.Hour))
+	retired.ExpiresAt = &stillPublished
+	expired := mustGenerate(t, AlgEdDSA, now.Add(-72*time.Hour))
+	expired.ExpiresAt = &gone
+	current := mustGenerate(t, AlgEdDSA, now.Add(-time.Hour))
+	keys := NewKeyring(retired, expired, current)
+
+	if k, err := keys.SigningKey(now); err != nil || k.ID != current.ID {
+		t.Fatalf("SigningKey() = %s, %v; want the current key", k.ID, err)
+	}
+
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(keys.JWKS(now), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	published := map[string]bool{}
+	for _, k := range jwks.Keys {
+		published[k.Kid] = true
+	}
+	if !published[retired.ID] || !published[current.ID] || published[expired.ID] {
+		t.Errorf("JWKS kids = %v, want the current and retired keys only", published)
+	}
+
+	if _, err := ParseAccessToken(keys, signWith(t, jwt.SigningMethodEdDSA, retired.ID, retired.private)); err != nil {
+		t.Errorf("token of the retired key: %v, want it accepted", err)
+	}
+	if _, err := ParseAccessToken(keys, signWith(t, jwt.SigningMethodEdDSA, expired.ID, expired.private)); err != ErrInvalidToken {
+		t.Errorf("token of the expired key: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestParseAccessTokenHMAC(t *testing.T) {
+	keys := NewKeyring(NewHMACKey([]byte("secret")))
+	if _, err := ParseAccessToken(keys, signWith(t, jwt.SigningMethodHS256, "", []byte("secret"))); err != nil {
+		t.Errorf("HS256 token: %v", err)
+	}
+	if _, err := ParseAccessToken(keys, signWith(t, jwt.SigningMethodHS256, "", []byte("other"))); err != ErrInvalidToken {
+		t.Errorf("wrong secret: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := ParseAccessToken(keys, signWith(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType)); err != ErrInvalidToken {
+		t.Errorf("alg none: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; ours are their last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfcSecret, totpStep(time.Unix(tt.unix, 0)))
+		if err != nil || got != tt.want {
+			t.Errorf("code at %d = %q, %v; want %q", tt.unix, got, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totpStep(now)
+	codeAt := func(s int64) string {
+		c, err := totpCode(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		want     int64
+		ok       bool
+	}{
+		{"current step", codeAt(step), 0, step, true},
+		{"one step behind", codeAt(step - 1), 0, step - 1, true},
+		{"one step ahead", codeAt(step + 1), 0, step + 1, true},
+		{"two steps behind", codeAt(step - 2), 0, 0, false},
+		{"two steps ahead", codeAt(step + 2), 0, 0, false},
+		{"surrounding spaces", " " + codeAt(step) + " ", 0, step, true},
+		{"replay of the last step", codeAt(step), step, 0, false},
+		{"older than the last step", codeAt(step - 1), step, 0, false},
+		{"newer than the last step", codeAt(step + 1), step, step + 1, true},
+		{"after an earlier step", codeAt(step), step - 1, step, true},
+		{"wrong code", "000000", 0, 0, false},
+		{"too short", codeAt(step)[:5], 0, 0, false},
+		{"too long", codeAt(step) + "0", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := VerifyTOTP(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("VerifyTOTP() = %d, %v; want %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := VerifyTOTP("not base32!", codeAt(step), now, 0); ok {
+		t.Error("VerifyTOTP accepted a code for an invalid secret")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("recovery code %q is not formatted xxxxx-xxxxx", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate recovery code %q", c)
+		}
+		seen[c] = true
+	}
+
+	want := HashRecoveryCode("abcde-fghij")
+	for _, typed := range []string{"abcdefghij", "ABCDE-FGHIJ", " abcde fghij "} {
+		if HashRecoveryCode(typed) != want {
+			t.Errorf("HashRecoveryCode(%q) does not match the stored form", typed)
+		}
+	}
+}
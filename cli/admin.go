@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Bannawat01/ec-space/handlers"
+)
+
+// Admin handles `admin -username NAME [-email EMAIL] [-password PASS]`.
+//
+// It promotes an existing user to the superuser role, or creates a new
+// superuser account when the username is free. The password may also come
+// from ADMIN_PASSWORD so it does not end up in shell history.
+func Admin(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	username := fs.String("username", "", "username to promote or create (required)")
+	email := fs.String("email", "", "email for a new account")
+	password := fs.String("password", "", "password for a new account (or set ADMIN_PASSWORD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *password == "" {
+		*password = os.Getenv("ADMIN_PASSWORD")
+	}
+
+	user, created, err := handlers.ProvisionAdmin(*username, *email, *password)
+	if err != nil {
+		return fmt.Errorf("admin: %w", err)
+	}
+	if created {
+		fmt.Printf("✅ Created superuser #%d (%s)\n", user.ID, user.Username)
+	} else {
+		fmt.Printf("✅ User #%d (%s) is now a superuser\n", user.ID, user.Username)
+	}
+	return nil
+}
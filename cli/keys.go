@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/handlers"
+)
+
+// Keys handles `keys list | rotate [-now]` for the RS256/EdDSA signing keys.
+//
+// rotate publishes a new key that starts signing after the usual publish
+// lead. With -now it signs at once and every older key is dropped, which
+// is what you want when a private key may have leaked.
+func Keys(args []string) error {
+	if config.App.Auth.JWTAlgorithm == auth.AlgHS256 {
+		return fmt.Errorf("keys: access tokens use HS256 (JWT_SECRET); set JWT_ALGORITHM to RS256 or EdDSA to use signing keys")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: keys list | rotate [-now]")
+	}
+
+	switch args[0] {
+	case "list":
+		rows, err := handlers.ListSigningKeys()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, k := range rows {
+			state := "active"
+			switch {
+			case k.ExpiresAt != nil && !now.Before(*k.ExpiresAt):
+				state = "expired"
+			case k.ActivatesAt.After(now):
+				state = "pending"
+			case k.ExpiresAt != nil:
+				state = "retiring"
+			}
+			expires := "-"
+			if k.ExpiresAt != nil {
+				expires = k.ExpiresAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-18s %-6s %-9s signs from %s  expires %s\n",
+				k.ID, k.Algorithm, state, k.ActivatesAt.Format("2006-01-02 15:04"), expires)
+		}
+	case "rotate":
+		fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+		now := fs.Bool("now", false, "sign with the new key immediately and drop all older keys")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := handlers.LoadSigningKeys(); err != nil {
+			return err
+		}
+		key, _, err := handlers.RotateSigningKeys(true, *now)
+		if err != nil {
+			return fmt.Errorf("keys rotate: %w", err)
+		}
+		fmt.Printf("✅ New %s key %s signs from %s\n", key.Algorithm, key.ID, key.ActivatesAt.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("unknown keys command %q (use list or rotate)", args[0])
+	}
+	return nil
+}
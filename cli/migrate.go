@@ -0,0 +1,60 @@
+// Package cli implements the maintenance subcommands of the server binary:
+//
+//	go run main.go migrate up | down [n] | status
+//	go run main.go admin -username NAME [-email EMAIL] [-password PASS]
+//	go run main.go keys list | rotate [-now]
+//	go run main.go mock-oidc [-addr :9999]
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/migrations"
+)
+
+// Migrate handles `migrate up|down [n]|status`.
+func Migrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [n] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(config.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %d migration(s) applied\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive number")
+			}
+			steps = v
+		}
+		n, err := migrations.Down(config.DB, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %d migration(s) reverted\n", n)
+	case "status":
+		list, err := migrations.List(config.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (use up, down or status)", args[0])
+	}
+	return nil
+}
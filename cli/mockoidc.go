@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bannawat01/ec-space/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MockOIDC handles `mock-oidc [-addr :9999] [-issuer URL] [-client-id ID]
+// [-client-secret SECRET]`.
+//
+// It runs a minimal OpenID Connect provider for local development: the
+// authorize page lets you type any email and choose whether it is verified,
+// and ID tokens are signed with an RSA key generated at startup. Never expose
+// it outside your machine.
+func MockOIDC(args []string) error {
+	fs := flag.NewFlagSet("mock-oidc", flag.ContinueOnError)
+	addr := fs.String("addr", ":9999", "listen address")
+	issuer := fs.String("issuer", "http://localhost:9999", "issuer URL clients are configured with")
+	clientID := fs.String("client-id", "ec-space", "accepted client_id")
+	clientSecret := fs.String("client-secret", "", "client secret to require (empty = public client)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("mock-oidc: %w", err)
+	}
+	m := &mockProvider{
+		issuer:       strings.TrimRight(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		kid:          "mock-" + time.Now().UTC().Format("20060102150405"),
+		codes:        map[string]mockGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+
+	log.Printf("🧪 Mock OIDC provider %s listening on %s (client_id %q)", m.issuer, *addr, m.clientID)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// mockGrant is what an authorization code stands for until it is redeemed.
+type mockGrant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	verified    bool
+	expires     time.Time
+}
+
+type mockProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+	kid          string
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                m.issuer,
+		"authorization_endpoint":                m.issuer + "/authorize",
+		"token_endpoint":                        m.issuer + "/token",
+		"jwks_uri":                              m.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(oidc.MarshalJWK(m.kid, &m.key.PublicKey))
+}
+
+var mockLoginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Mock OIDC</title></head>
+<body style="font-family:sans-serif;max-width:420px;margin:60px auto">
+<h2>Mock OIDC sign-in</h2>
+<form method="post">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<p><label>Email<br><input name="email" type="email" required style="width:100%"></label></p>
+<p><label><input name="email_verified" type="checkbox" value="true" checked> email verified</label></p>
+<p><button name="decision" value="allow">Sign in</button> <button name="decision" value="deny">Cancel</button></p>
+</form></body></html>`))
+
+// authorize shows a sign-in form on GET and issues a code on POST.
+func (m *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	q := r.Form
+	if q.Get("client_id") != m.clientID || q.Get("response_type") != "code" || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		params := url.Values{}
+		for _, k := range []string{"client_id", "response_type", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params.Set(k, q.Get(k))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		mockLoginPage.Execute(w, params)
+		return
+	}
+
+	back, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+	out := back.Query()
+	out.Set("state", q.Get("state"))
+	if q.Get("decision") != "allow" {
+		out.Set("error", "access_denied")
+	} else {
+		code, err := oidc.RandomString(24)
+		if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		m.mu.Lock()
+		m.codes[code] = mockGrant{
+			redirectURI: q.Get("redirect_uri"),
+			challenge:   q.Get("code_challenge"),
+			nonce:       q.Get("nonce"),
+			email:       strings.TrimSpace(q.Get("email")),
+			verified:    q.Get("email_verified") == "true",
+			expires:     time.Now().Add(time.Minute),
+		}
+		m.mu.Unlock()
+		out.Set("code", code)
+	}
+	back.RawQuery = out.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking the client, redirect URI and PKCE
+// verifier the same way a real provider would.
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if !m.clientAuthenticated(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(grant.expires):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case grant.redirectURI != r.PostForm.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	subject := sha256.Sum256([]byte(strings.ToLower(grant.email)))
+	username, _, _ := strings.Cut(grant.email, "@")
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                m.issuer,
+		"sub":                base64.RawURLEncoding.EncodeToString(subject[:12]),
+		"aud":                m.clientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              grant.nonce,
+		"email":              grant.email,
+		"email_verified":     grant.verified,
+		"preferred_username": username,
+	})
+	tok.Header["kid"] = m.kid
+	idToken, err := tok.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	access, _ := oidc.RandomString(24)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockProvider) clientAuthenticated(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != m.clientID {
+		return false
+	}
+	return m.clientSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(m.clientSecret)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
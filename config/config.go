@@ -0,0 +1,507 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Default secrets shipped with the repo. They are fine for local development
+// but the server refuses to start with them when Env is "production".
+const (
+	DefaultDBPassword    = "super_secret_password"
+	DefaultJWTSecret     = "galactic_secret_key_99"
+	DefaultWebhookSecret = "dev_webhook_secret"
+)
+
+// Config is the typed application configuration.
+//
+// Values are resolved in this order, later ones winning:
+//  1. built-in defaults (Default)
+//  2. an optional YAML or TOML file (CONFIG_FILE, picked by extension)
+//  3. environment variables (see applyEnv)
+type Config struct {
+	Env      string         `yaml:"env" toml:"env"`
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	Payments PaymentsConfig `yaml:"payments" toml:"payments"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	// OIDC lists the external identity providers users can sign in with,
+	// keyed by the name used in /api/oidc/:provider/login.
+	OIDC map[string]OIDCProviderConfig `yaml:"oidc" toml:"oidc"`
+}
+
+type ServerConfig struct {
+	Port        string   `yaml:"port" toml:"port"`
+	CORSOrigins []string `yaml:"cors_origins" toml:"cors_origins"`
+	UploadDir   string   `yaml:"upload_dir" toml:"upload_dir"`
+	// TrustedProxies may set X-Forwarded-For. Empty means the client IP is
+	// always the direct peer, so it can't be spoofed to dodge login throttling.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	// FrontendURL is the base of links sent by email (reset password, verify email).
+	FrontendURL string `yaml:"frontend_url" toml:"frontend_url"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+	// URL, when set, is used as-is and overrides the fields above.
+	URL string `yaml:"url" toml:"url"`
+	// AutoMigrate applies pending migrations when the server starts.
+	// When false the server refuses to start until `migrate up` has been run.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	// JWTSecret signs access tokens when JWTAlgorithm is HS256. With RS256
+	// or EdDSA it encrypts the private signing keys stored in the database.
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret"`
+	// JWTAlgorithm is HS256, RS256 or EdDSA. The asymmetric ones publish
+	// their public keys at /.well-known/jwks.json.
+	JWTAlgorithm string `yaml:"jwt_algorithm" toml:"jwt_algorithm"`
+	// JWTKeyRotation is how often a new signing key is generated (RS256 and
+	// EdDSA only). 0 turns scheduled rotation off.
+	JWTKeyRotation  Duration `yaml:"jwt_key_rotation" toml:"jwt_key_rotation"`
+	AccessTokenTTL  Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	// Lifetime of the single-use links sent by email.
+	PasswordResetTTL Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	EmailVerifyTTL   Duration `yaml:"email_verify_ttl" toml:"email_verify_ttl"`
+	// TOTPIssuer is the account name shown in authenticator apps.
+	TOTPIssuer string `yaml:"totp_issuer" toml:"totp_issuer"`
+	// Failed logins allowed per username and per client IP before a
+	// temporary lockout of LoginLockout.
+	LoginMaxAttempts   int      `yaml:"login_max_attempts" toml:"login_max_attempts"`
+	LoginIPMaxAttempts int      `yaml:"login_ip_max_attempts" toml:"login_ip_max_attempts"`
+	LoginLockout       Duration `yaml:"login_lockout" toml:"login_lockout"`
+}
+
+// Duration is a time.Duration written as "15m" or "24h" in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+type PaymentsConfig struct {
+	Provider        string `yaml:"provider" toml:"provider"`
+	WebhookSecret   string `yaml:"webhook_secret" toml:"webhook_secret"`
+	FakeAutoConfirm bool   `yaml:"fake_auto_confirm" toml:"fake_auto_confirm"`
+	// Stripe is used when Provider is "stripe"; WebhookSecret is then the
+	// signing secret of the Stripe webhook endpoint.
+	Stripe StripeConfig `yaml:"stripe" toml:"stripe"`
+	// Top-up limits in whole credits.
+	TopupMin   int64 `yaml:"topup_min" toml:"topup_min"`
+	TopupMax   int64 `yaml:"topup_max" toml:"topup_max"`
+	TopupDaily int64 `yaml:"topup_daily" toml:"topup_daily"`
+}
+
+type StripeConfig struct {
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	// Currency is a lowercase ISO code with two decimal places, e.g. "thb".
+	Currency string `yaml:"currency" toml:"currency"`
+}
+
+// MailConfig selects how outgoing email is delivered: "smtp" sends it, "file"
+// writes .eml files to Dir and "log" prints it to the server log.
+type MailConfig struct {
+	Driver string     `yaml:"driver" toml:"driver"`
+	From   string     `yaml:"from" toml:"from"`
+	Dir    string     `yaml:"dir" toml:"dir"`
+	SMTP   SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// OIDCProviderConfig is one OpenID Connect identity provider. Endpoints are
+// read from Issuer + "/.well-known/openid-configuration".
+type OIDCProviderConfig struct {
+	DisplayName  string `yaml:"display_name" toml:"display_name"`
+	Issuer       string `yaml:"issuer" toml:"issuer"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// RedirectURL must point at /api/oidc/<name>/callback on this server and
+	// be registered with the provider.
+	RedirectURL string   `yaml:"redirect_url" toml:"redirect_url"`
+	Scopes      []string `yaml:"scopes" toml:"scopes"`
+}
+
+// App is the configuration loaded at startup.
+var App = Default()
+
+// Default returns the development configuration.
+func Default() *Config {
+	return &Config{
+		Env: "development",
+		Server: ServerConfig{
+			Port:        "8080",
+			CORSOrigins: []string{"http://localhost:5173", "http://localhost:5174"},
+			UploadDir:   "uploads",
+			FrontendURL: "http://localhost:5173",
+		},
+		Database: DatabaseConfig{
+			Host:        "localhost",
+			Port:        5432,
+			User:        "galaxy_admin",
+			Password:    DefaultDBPassword,
+			Name:        "weapon_store",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		Auth: AuthConfig{
+			JWTSecret:          DefaultJWTSecret,
+			JWTAlgorithm:       "HS256",
+			JWTKeyRotation:     Duration{30 * 24 * time.Hour},
+			AccessTokenTTL:     Duration{15 * time.Minute},
+			RefreshTokenTTL:    Duration{30 * 24 * time.Hour},
+			PasswordResetTTL:   Duration{time.Hour},
+			EmailVerifyTTL:     Duration{48 * time.Hour},
+			TOTPIssuer:         "EC-Space",
+			LoginMaxAttempts:   10,
+			LoginIPMaxAttempts: 100,
+			LoginLockout:       Duration{15 * time.Minute},
+		},
+		Payments: PaymentsConfig{
+			Provider:        "fake",
+			WebhookSecret:   DefaultWebhookSecret,
+			FakeAutoConfirm: true,
+			Stripe:          StripeConfig{Currency: "thb"},
+			TopupMin:        10,
+			TopupMax:        50000,
+			TopupDaily:      100000,
+		},
+		Mail: MailConfig{
+			Driver: "log",
+			From:   "EC-Space <no-reply@ec-space.local>",
+			Dir:    "mail_outbox",
+			SMTP:   SMTPConfig{Port: 587},
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the optional file named by
+// CONFIG_FILE and the environment, validates it and stores it in App.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	App = cfg
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields from environment variables.
+func (c *Config) applyEnv() error {
+	str := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	var errs []error
+	integer := func(key string, dst *int64) {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", key, err))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	str("APP_ENV", &c.Env)
+
+	str("PORT", &c.Server.Port)
+	if v, ok := os.LookupEnv("CORS_ORIGINS"); ok {
+		c.Server.CORSOrigins = splitList(v)
+	}
+	str("UPLOAD_DIR", &c.Server.UploadDir)
+	str("FRONTEND_URL", &c.Server.FrontendURL)
+	if v, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		c.Server.TrustedProxies = splitList(v)
+	}
+
+	str("DATABASE_URL", &c.Database.URL)
+	str("DB_HOST", &c.Database.Host)
+	if v, ok := os.LookupEnv("DB_PORT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: DB_PORT: %w", err))
+		}
+		c.Database.Port = n
+	}
+	str("DB_USER", &c.Database.User)
+	str("DB_PASSWORD", &c.Database.Password)
+	str("DB_NAME", &c.Database.Name)
+	str("DB_SSLMODE", &c.Database.SSLMode)
+	if v, ok := os.LookupEnv("DB_AUTO_MIGRATE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: DB_AUTO_MIGRATE: %w", err))
+		}
+		c.Database.AutoMigrate = b
+	}
+
+	str("JWT_SECRET", &c.Auth.JWTSecret)
+	duration := func(key string, dst *Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", key, err))
+				return
+			}
+			*dst = Duration{d}
+		}
+	}
+	str("JWT_ALGORITHM", &c.Auth.JWTAlgorithm)
+	duration("JWT_KEY_ROTATION", &c.Auth.JWTKeyRotation)
+	duration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	duration("REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL)
+	duration("PASSWORD_RESET_TTL", &c.Auth.PasswordResetTTL)
+	duration("EMAIL_VERIFY_TTL", &c.Auth.EmailVerifyTTL)
+	str("TOTP_ISSUER", &c.Auth.TOTPIssuer)
+	for key, dst := range map[string]*int{
+		"LOGIN_MAX_ATTEMPTS":    &c.Auth.LoginMaxAttempts,
+		"LOGIN_IP_MAX_ATTEMPTS": &c.Auth.LoginIPMaxAttempts,
+	} {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", key, err))
+			}
+			*dst = n
+		}
+	}
+	duration("LOGIN_LOCKOUT", &c.Auth.LoginLockout)
+
+	str("PAYMENT_PROVIDER", &c.Payments.Provider)
+	str("PAYMENT_WEBHOOK_SECRET", &c.Payments.WebhookSecret)
+	if v, ok := os.LookupEnv("PAYMENT_FAKE_AUTO_CONFIRM"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: PAYMENT_FAKE_AUTO_CONFIRM: %w", err))
+		}
+		c.Payments.FakeAutoConfirm = b
+	}
+	str("STRIPE_SECRET_KEY", &c.Payments.Stripe.SecretKey)
+	str("STRIPE_CURRENCY", &c.Payments.Stripe.Currency)
+	integer("TOPUP_MIN", &c.Payments.TopupMin)
+	integer("TOPUP_MAX", &c.Payments.TopupMax)
+	integer("TOPUP_DAILY", &c.Payments.TopupDaily)
+
+	str("MAIL_DRIVER", &c.Mail.Driver)
+	str("MAIL_FROM", &c.Mail.From)
+	str("MAIL_DIR", &c.Mail.Dir)
+	str("SMTP_HOST", &c.Mail.SMTP.Host)
+	if v, ok := os.LookupEnv("SMTP_PORT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: SMTP_PORT: %w", err))
+		}
+		c.Mail.SMTP.Port = n
+	}
+	str("SMTP_USERNAME", &c.Mail.SMTP.Username)
+	str("SMTP_PASSWORD", &c.Mail.SMTP.Password)
+
+	// OIDC_PROVIDERS=google,mock then OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID, ...
+	if v, ok := os.LookupEnv("OIDC_PROVIDERS"); ok {
+		if c.OIDC == nil {
+			c.OIDC = map[string]OIDCProviderConfig{}
+		}
+		for _, name := range splitList(v) {
+			p := c.OIDC[name]
+			prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+			str(prefix+"DISPLAY_NAME", &p.DisplayName)
+			str(prefix+"ISSUER", &p.Issuer)
+			str(prefix+"CLIENT_ID", &p.ClientID)
+			str(prefix+"CLIENT_SECRET", &p.ClientSecret)
+			str(prefix+"REDIRECT_URL", &p.RedirectURL)
+			if v, ok := os.LookupEnv(prefix + "SCOPES"); ok {
+				p.Scopes = splitList(v)
+			}
+			c.OIDC[name] = p
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsProduction reports whether the server runs in production mode.
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// Validate checks the configuration and, in production, rejects the
+// development secrets that ship with the repo.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Env != "development" && c.Env != "production" && c.Env != "test" {
+		errs = append(errs, fmt.Errorf("config: env must be development, production or test (got %q)", c.Env))
+	}
+	if _, err := strconv.Atoi(c.Server.Port); err != nil {
+		errs = append(errs, fmt.Errorf("config: server.port must be a number (got %q)", c.Server.Port))
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("config: server.cors_origins must not be empty"))
+	}
+	if c.Database.URL == "" && (c.Database.Host == "" || c.Database.Name == "" || c.Database.User == "") {
+		errs = append(errs, errors.New("config: database host, name and user are required"))
+	}
+	if len(c.Auth.JWTSecret) < 16 {
+		errs = append(errs, errors.New("config: auth.jwt_secret must be at least 16 characters"))
+	}
+	switch c.Auth.JWTAlgorithm {
+	case "HS256", "RS256", "EdDSA":
+	default:
+		errs = append(errs, fmt.Errorf("config: auth.jwt_algorithm must be HS256, RS256 or EdDSA (got %q)", c.Auth.JWTAlgorithm))
+	}
+	if r := c.Auth.JWTKeyRotation.Duration; r != 0 && r < 24*time.Hour {
+		errs = append(errs, errors.New("config: auth.jwt_key_rotation must be 0 (off) or at least 24h"))
+	}
+	if c.Auth.AccessTokenTTL.Duration <= 0 || c.Auth.RefreshTokenTTL.Duration <= c.Auth.AccessTokenTTL.Duration {
+		errs = append(errs, errors.New("config: auth token TTLs must satisfy 0 < access_token_ttl < refresh_token_ttl"))
+	}
+	if c.Auth.PasswordResetTTL.Duration <= 0 || c.Auth.EmailVerifyTTL.Duration <= 0 {
+		errs = append(errs, errors.New("config: auth.password_reset_ttl and auth.email_verify_ttl must be positive"))
+	}
+	if c.Auth.TOTPIssuer == "" || strings.Contains(c.Auth.TOTPIssuer, ":") {
+		errs = append(errs, errors.New("config: auth.totp_issuer must be set and must not contain ':'"))
+	}
+	if c.Auth.LoginMaxAttempts <= 0 || c.Auth.LoginIPMaxAttempts <= 0 || c.Auth.LoginLockout.Duration <= 0 {
+		errs = append(errs, errors.New("config: auth.login_max_attempts, login_ip_max_attempts and login_lockout must be positive"))
+	}
+	if c.Server.FrontendURL == "" {
+		errs = append(errs, errors.New("config: server.frontend_url must not be empty"))
+	}
+	switch c.Mail.Driver {
+	case "log", "file":
+	case "smtp":
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 {
+			errs = append(errs, errors.New("config: mail.smtp.host and mail.smtp.port are required for the smtp driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("config: mail.driver must be smtp, file or log (got %q)", c.Mail.Driver))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("config: mail.from must not be empty"))
+	}
+	for name, o := range c.OIDC {
+		if name == "" || strings.ContainsAny(name, "/?#") {
+			errs = append(errs, fmt.Errorf("config: oidc provider name %q is not allowed", name))
+		}
+		if o.Issuer == "" || o.ClientID == "" || o.RedirectURL == "" {
+			errs = append(errs, fmt.Errorf("config: oidc.%s needs issuer, client_id and redirect_url", name))
+		}
+		if c.IsProduction() && !strings.HasPrefix(o.Issuer, "https://") {
+			errs = append(errs, fmt.Errorf("config: oidc.%s issuer must use https in production", name))
+		}
+	}
+	p := c.Payments
+	switch p.Provider {
+	case "fake":
+	case "stripe":
+		if p.Stripe.SecretKey == "" || p.Stripe.Currency == "" {
+			errs = append(errs, errors.New("config: payments.stripe.secret_key and payments.stripe.currency are required for the stripe provider"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("config: payments.provider must be stripe or fake (got %q)", p.Provider))
+	}
+	if p.TopupMin <= 0 || p.TopupMax < p.TopupMin || p.TopupDaily < p.TopupMax {
+		errs = append(errs, errors.New("config: top-up limits must satisfy 0 < min <= max <= daily"))
+	}
+
+	if c.IsProduction() {
+		if c.Auth.JWTSecret == DefaultJWTSecret {
+			errs = append(errs, errors.New("config: refusing to start in production with the default JWT secret"))
+		}
+		if c.Database.URL == "" && c.Database.Password == DefaultDBPassword {
+			errs = append(errs, errors.New("config: refusing to start in production with the default database password"))
+		}
+		if p.WebhookSecret == DefaultWebhookSecret {
+			errs = append(errs, errors.New("config: refusing to start in production with the default payment webhook secret"))
+		}
+		if p.Provider == "fake" {
+			errs = append(errs, errors.New("config: the fake payment provider cannot be used in production"))
+		}
+		if c.Mail.Driver != "smtp" {
+			errs = append(errs, errors.New("config: production must send mail with the smtp driver"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DSN returns the Postgres connection string.
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
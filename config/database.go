@@ -3,27 +3,24 @@ package config
 import (
 	"fmt"
 
-	"github.com/Bannawat01/ec-space/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func InitDB() {
-	dsn := "host=localhost user=galaxy_admin password=super_secret_password dbname=weapon_store port=5432 sslmode=disable"
+// InitDB connects to Postgres. The schema itself is managed by the versioned
+// SQL files in the migrations package, not by AutoMigrate.
+func InitDB(cfg DatabaseConfig) {
 	var err error
 
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 
 	if err != nil {
 		panic("Failed to connect to intergalactic database!")
 	}
 
-	// AutoMigrate all models
-	DB.AutoMigrate(&models.Weapon{}, &models.User{}, &models.Order{}, &models.OrderItem{}, &models.CartItem{})
-
-	fmt.Println("🚀 Database Connected and Migrated Successfully!")
+	fmt.Println("🚀 Database Connected Successfully!")
 }
 
 func GetDB() *gorm.DB {
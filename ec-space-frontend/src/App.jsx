@@ -11,6 +11,10 @@ import Cart from './pages/Cart';
 import Topup from './pages/Topup';
 import OrderHistory from './pages/OrderHistory';
 import Profile from './pages/Profile';
+import ForgotPassword from './pages/ForgotPassword';
+import ResetPassword from './pages/ResetPassword';
+import VerifyEmail from './pages/VerifyEmail';
+import OidcCallback from './pages/OidcCallback';
 
 function App() {
   return (
@@ -38,6 +42,10 @@ function App() {
               <Route path="/topup" element={<Topup />} />
               <Route path="/profile" element={<Profile />} />
               <Route path="/history" element={<OrderHistory />} />
+              <Route path="/forgot-password" element={<ForgotPassword />} />
+              <Route path="/reset-password" element={<ResetPassword />} />
+              <Route path="/verify-email" element={<VerifyEmail />} />
+              <Route path="/oidc/callback" element={<OidcCallback />} />
             </Routes>
           </main>
           <Footer />
@@ -0,0 +1,82 @@
+import { useEffect, useState } from 'react';
+import api from '../services/api';
+
+const toast = (message, type = 'info') =>
+  window.dispatchEvent(new CustomEvent('appToast', { detail: { message, type } }));
+
+// รายการอุปกรณ์ที่ล็อกอินอยู่ พร้อมปุ่มออกจากระบบทีละเครื่องหรือทุกเครื่องยกเว้นเครื่องนี้
+export default function ActiveSessions() {
+  const [sessions, setSessions] = useState([]);
+
+  const load = () =>
+    api.get('/sessions')
+      .then((res) => setSessions(res.data))
+      .catch(() => setSessions([]));
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  const endSession = async (s) => {
+    try {
+      await api.delete(`/sessions/${s.id}`);
+      if (s.current) {
+        localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
+        window.location.href = '/login';
+        return;
+      }
+      toast('ออกจากระบบอุปกรณ์นั้นเรียบร้อย');
+      load();
+    } catch (err) {
+      toast(err.response?.data?.error || 'ยกเลิก session ไม่สำเร็จ', 'error');
+    }
+  };
+
+  const endOthers = async () => {
+    try {
+      await api.delete('/sessions');
+      toast('ออกจากระบบอุปกรณ์อื่นทั้งหมดเรียบร้อย');
+      load();
+    } catch (err) {
+      toast(err.response?.data?.error || 'ยกเลิก session ไม่สำเร็จ', 'error');
+    }
+  };
+
+  return (
+    <div className="mt-8 p-6 md:p-8 !bg-black/60 backdrop-blur-xl border border-cyan-500/25 rounded-lg">
+      <div className="flex items-center justify-between mb-4">
+        <p className="font-mono text-[10px] uppercase tracking-widest text-cyan-400/70">Active Sessions</p>
+        {sessions.length > 1 && (
+          <button
+            onClick={endOthers}
+            className="!bg-red-500/15 !text-red-200 !border !border-red-400/50 hover:!bg-red-500/30 px-4 py-1.5 rounded-lg font-black uppercase tracking-wider text-[10px] transition-all"
+          >
+            Sign out other devices
+          </button>
+        )}
+      </div>
+      <ul className="space-y-3">
+        {sessions.map((s) => (
+          <li key={s.id} className="flex items-center justify-between gap-4 p-3 border border-cyan-500/15 rounded-lg">
+            <div>
+              <p className="text-sm font-bold">
+                {s.device}
+                {s.current && <span className="ml-2 text-[10px] text-cyan-300 uppercase tracking-widest">This device</span>}
+              </p>
+              <p className="font-mono text-[10px] text-white/40">
+                {s.ip} · last seen {s.last_seen_at ? new Date(s.last_seen_at).toLocaleString() : '-'}
+              </p>
+            </div>
+            <button
+              onClick={() => endSession(s)}
+              className="!bg-slate-800/85 !text-slate-100 !border !border-slate-500/70 hover:!border-red-400/60 hover:!text-red-200 px-4 py-1.5 rounded-lg font-black uppercase tracking-wider text-[10px] transition-all"
+            >
+              {s.current ? 'Log out' : 'Sign out'}
+            </button>
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+}
@@ -119,7 +119,8 @@ function Navbar() {
               <Link to="/profile" className="hidden sm:block whitespace-nowrap text-[11px] font-black uppercase tracking-[0.2em] text-white/85 hover:text-cyan-300 transition-colors">{t(username)}</Link>
 
               <button
-                onClick={() => {
+                onClick={async () => {
+                  try { await api.post('/logout'); } catch { /* token อาจหมดอายุไปแล้ว */ }
                   localStorage.clear();
                   navigate('/login');
                   window.location.reload();
@@ -1,3 +0,0 @@
-const categories = ['All','Plasma','Kinetic / Railgun','Laser','Sonic','Sniper','Melee','Standard'];
-
-export default categories;
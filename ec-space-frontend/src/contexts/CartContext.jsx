@@ -14,14 +14,19 @@ export const CartProvider = ({ children }) => {
       const data = Array.isArray(response.data) ? response.data : (response.data.items || []);
       
       const formattedCart = data
-        .filter(item => item && item.weapon)
+        .filter(item => item && item.weapon && item.variant)
         .map(item => ({
           ...item.weapon,
+          // ราคาและตัวเลือกมาจากรุ่น (variant) ที่เลือกไว้
+          price: item.variant.unit_price,
+          variant_id: item.variant_id,
+          sku: item.variant.sku,
+          options: item.variant.options || {},
           quantity: item.quantity,
           cart_item_id: item.id
         }))
         // 🌟 เพิ่มจุดนี้: เรียงลำดับตาม ID เสมอ เพื่อไม่ให้รายการเด้งไปมา
-        .sort((a, b) => a.id - b.id); 
+        .sort((a, b) => a.id - b.id || a.variant_id - b.variant_id); 
 
       setCart(formattedCart);
     } catch (error) {
@@ -34,8 +39,8 @@ export const CartProvider = ({ children }) => {
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
-  // 🛒 2. เพิ่มสินค้า (ส่งค่าบวกปกติ)
- const addToCart = async (weapon, customQuantity = 1) => {
+  // 🛒 2. เพิ่มสินค้า (ส่งค่าบวกปกติ) — ส่ง variant มาด้วยถ้าอาวุธมีหลายรุ่น
+ const addToCart = async (weapon, customQuantity = 1, variant = null) => {
   const token = localStorage.getItem('token');
   if (!token) {
     alert("กรุณาล็อกอินก่อนเลือกสินค้า");
@@ -50,6 +55,7 @@ export const CartProvider = ({ children }) => {
 
     const response = await api.post('/cart', {
       weapon_id: weaponId,
+      ...(variant ? { variant_id: Number(variant.id) } : {}),
       quantity: qtyToAdd
     });
 
@@ -68,24 +74,24 @@ export const CartProvider = ({ children }) => {
   }
 };
 
-  const updateQuantity = async (weaponId, newQuantity) => {
+  const updateQuantity = async (variantId, newQuantity) => {
     const targetQty = Number(newQuantity);
-    if (targetQty < 1) return removeFromCart(weaponId);
+    if (targetQty < 1) return removeFromCart(variantId);
 
     try {
       const token = localStorage.getItem('token');
       
       // 1. ค้นหาไอเทมเฉพาะตัวที่เราจะกดเท่านั้น
-      const itemInCart = cart.find(item => Number(item.id) === Number(weaponId));
+      const itemInCart = cart.find(item => Number(item.variant_id) === Number(variantId));
       if (!itemInCart) return;
 
       // 2. ดำเนินการลบและเพิ่มใหม่ตามกลยุทธ์ Re-sync (ป้องกัน Stock Error)
-      await api.delete(`/cart/${weaponId}`, {
+      await api.delete(`/cart/${variantId}`, {
         headers: { Authorization: `Bearer ${token}` }
       }); 
 
       await api.post('/cart', {
-        weapon_id: Number(weaponId),
+        variant_id: Number(variantId),
         quantity: targetQty 
       }, {
         headers: { Authorization: `Bearer ${token}` }
@@ -95,17 +101,17 @@ export const CartProvider = ({ children }) => {
       // เพื่อให้แน่ใจว่าสินค้าตัวอื่นยังคงค่าเดิมไว้ ไม่โดนเขียนทับใน State
       await fetchCart(); 
       
-      console.log(`✅ Updated only item ${weaponId} to ${targetQty}`);
+      console.log(`✅ Updated only item ${variantId} to ${targetQty}`);
     } catch (error) {
       console.error("Update failed:", error.message);
     }
   };
 
   // 🗑️ 4. ลบสินค้า
-  const removeFromCart = async (weaponId) => {
+  const removeFromCart = async (variantId) => {
     try {
-      // Backend ของคุณใช้ Delete โดยอ้างอิง Weapon ID
-      await api.delete(`/cart/${weaponId}`);
+      // Backend ลบโดยอ้างอิง Variant ID (รุ่นของสินค้า)
+      await api.delete(`/cart/${variantId}`);
       await fetchCart();
     } catch (error) {
       console.error("Delete failed:", error);
@@ -1,29 +1,29 @@
 import { useEffect, useState, useRef } from 'react';
 import { ChevronDown, Hash, RefreshCw, Trash2, Check, DollarSign, Upload, Image as ImageIcon } from 'lucide-react';
 import api from '../services/api';
-import categories from '../constants/categories';
 
 // ── Category badge color map ──────────────────────────────────
 const BADGE_CLS = {
-  Plasma: 'bg-cyan-500/15 text-cyan-400 border-cyan-500/30',
-  'Kinetic / Railgun': 'bg-amber-500/15 text-amber-400 border-amber-500/30',
-  Laser: 'bg-red-500/15 text-red-400 border-red-500/30',
-  Sonic: 'bg-blue-500/15 text-blue-400 border-blue-500/30',
-  Sniper: 'bg-purple-500/15 text-purple-400 border-purple-500/30',
-  Melee: 'bg-orange-500/15 text-orange-400 border-orange-500/30',
-  Standard: 'bg-slate-500/15 text-slate-400 border-slate-500/30',
+  plasma: 'bg-cyan-500/15 text-cyan-400 border-cyan-500/30',
+  'kinetic-railgun': 'bg-amber-500/15 text-amber-400 border-amber-500/30',
+  laser: 'bg-red-500/15 text-red-400 border-red-500/30',
+  sonic: 'bg-blue-500/15 text-blue-400 border-blue-500/30',
+  sniper: 'bg-purple-500/15 text-purple-400 border-purple-500/30',
+  melee: 'bg-orange-500/15 text-orange-400 border-orange-500/30',
+  standard: 'bg-slate-500/15 text-slate-400 border-slate-500/30',
 };
 
-function CategoryBadge({ type }) {
+function CategoryBadge({ type, label }) {
   const cls = BADGE_CLS[type] ?? 'bg-white/10 text-white/50 border-white/20';
   return (
     <span className={`inline-flex items-center px-2 py-0.5 rounded border text-[9px] font-black tracking-widest uppercase ${cls}`}>
-      {type}
+      {label ?? type}
     </span>
   );
 }
 
-function TacticalDropdown({ value, onChange, options }) {
+// options are category slugs; labels maps a slug to its display name
+function TacticalDropdown({ value, onChange, options, labels = {} }) {
   const [isOpen, setIsOpen] = useState(false);
   const containerRef = useRef(null);
 
@@ -43,7 +43,7 @@ function TacticalDropdown({ value, onChange, options }) {
         style={{ backgroundColor: '#000000', color: '#ffffff', opacity: 1 }}
         className="flex items-center justify-between w-full border-2 border-cyan-500 rounded-lg px-3 py-2.5 text-[11px] outline-none transition-all shadow-[0_0_15px_rgba(6,182,212,0.4)] font-black"
       >
-        <span className="truncate uppercase tracking-[0.12em]">{value || "SELECT"}</span>
+        <span className="truncate uppercase tracking-[0.12em]">{labels[value] ?? value ?? "SELECT"}</span>
         <ChevronDown className={`w-4 h-4 text-cyan-400 transition-transform duration-300 ${isOpen ? 'rotate-180' : ''}`} />
       </button>
 
@@ -53,14 +53,14 @@ function TacticalDropdown({ value, onChange, options }) {
           className="absolute z-[1001] top-full mt-2 w-full shadow-[0_20px_50px_rgba(0,0,0,1)] rounded-xl overflow-hidden"
         >
           <div className="max-h-[200px] overflow-y-auto custom-scrollbar">
-            {options.filter(c => c !== 'All').map(opt => (
+            {options.map(opt => (
               <div
                 key={opt}
                 onClick={() => { onChange(opt); setIsOpen(false); }}
                 style={{ color: '#ffffff', backgroundColor: '#000000' }}
                 className={`px-4 py-3 text-[11px] cursor-pointer flex items-center justify-between border-b border-white/5 last:border-0 hover:bg-cyan-500/30 ${value === opt ? 'bg-cyan-500/40 font-black' : ''}`}
               >
-                <span className="uppercase tracking-[0.15em] font-bold">{opt}</span>
+                <span className="uppercase tracking-[0.15em] font-bold">{labels[opt] ?? opt}</span>
                 {value === opt && <Check className="w-3.5 h-3.5 text-cyan-400" />}
               </div>
             ))}
@@ -82,7 +82,7 @@ function FormField({ label, children }) {
   );
 }
 
-function WeaponRow({ weapon, onEdit, onUpdate, onDelete }) {
+function WeaponRow({ weapon, categories, onEdit, onUpdate, onDelete }) {
   const [localType, setLocalType] = useState(weapon.type);
   const [dirty, setDirty] = useState(false);
 
@@ -107,8 +107,8 @@ function WeaponRow({ weapon, onEdit, onUpdate, onDelete }) {
       </div>
 
       <div className="flex flex-col gap-2 pt-0.5">
-        <CategoryBadge type={localType} />
-        <TacticalDropdown value={localType} onChange={(val) => field('type', val)} options={categories} />
+        <CategoryBadge type={localType} label={categories.labels[localType]} />
+        <TacticalDropdown value={localType} onChange={(val) => field('type', val)} options={categories.slugs} labels={categories.labels} />
       </div>
 
       <div className="pt-0.5">
@@ -153,15 +153,25 @@ function Admin() {
   const [weapons, setWeapons] = useState([]);
   const [editData, setEditData] = useState({});
   const [panelOpen, setPanelOpen] = useState(false);
-  const [newWeapon, setNewWeapon] = useState({ name: '', type: 'Standard', price: '', stock: '', description: '', image: null });
+  const [newWeapon, setNewWeapon] = useState({ name: '', type: 'standard', price: '', stock: '', description: '', image: null });
   const [orders, setOrders] = useState([]);
+  const [categories, setCategories] = useState({ slugs: [], labels: {}, ids: {} });
+  // spec ของหมวดหมู่ที่เลือกในฟอร์มเพิ่มอาวุธ และค่าที่กรอก
+  const [specAttrs, setSpecAttrs] = useState([]);
+  const [newSpecs, setNewSpecs] = useState({});
 
   const username = localStorage.getItem('username');
 
   const fetchWeapons = async () => {
     try {
-      const res = await api.get('/weapons');
-      setWeapons(res.data);
+      // the catalog is paginated; the admin table needs every weapon
+      const all = [];
+      for (let page = 1; ; page++) {
+        const res = await api.get('/weapons', { params: { page, page_size: 100, sort: 'name' } });
+        all.push(...res.data.items);
+        if (all.length >= res.data.total || res.data.items.length === 0) break;
+      }
+      setWeapons(all);
     } catch (err) { console.error('Fetch error:', err); }
   };
 
@@ -173,8 +183,27 @@ function Admin() {
     } catch (err) { console.error('Orders fetch error:', err); }
   };
 
-  useEffect(() => { fetchWeapons(); }, []);
+  const fetchCategories = async () => {
+    try {
+      const res = await api.get('/categories');
+      setCategories({
+        slugs: res.data.map(c => c.slug),
+        labels: Object.fromEntries(res.data.map(c => [c.slug, `${c.depth > 0 ? '↳ ' : ''}${c.name}`])),
+        ids: Object.fromEntries(res.data.map(c => [c.slug, c.id])),
+      });
+    } catch (err) { console.error('Categories fetch error:', err); }
+  };
+
+  useEffect(() => { fetchWeapons(); fetchCategories(); }, []);
   useEffect(() => { if (tab === 'orders') fetchOrders(); }, [tab]);
+  useEffect(() => {
+    setNewSpecs({});
+    const id = categories.ids[newWeapon.type];
+    if (!id) { setSpecAttrs([]); return; }
+    api.get(`/categories/${id}/specs`)
+      .then(res => setSpecAttrs(res.data))
+      .catch(() => setSpecAttrs([]));
+  }, [newWeapon.type, categories]);
 
   const handleUpdateWeapon = async (id) => {
     const data = editData[id];
@@ -197,6 +226,14 @@ function Admin() {
     Object.entries(newWeapon).forEach(([k, v]) => {
       if (v !== null) fd.append(k, v);
     });
+    // spec ส่งเป็น JSON ตามชนิดของแต่ละตัว (ช่องที่เว้นว่างไม่ส่ง)
+    const specs = {};
+    specAttrs.forEach(a => {
+      const v = newSpecs[a.key];
+      if (v === undefined || v === '') return;
+      specs[a.key] = a.type === 'number' || a.type === 'integer' ? Number(v) : v;
+    });
+    fd.append('specs', JSON.stringify(specs));
 
     try {
       const token = localStorage.getItem('token');
@@ -207,10 +244,11 @@ function Admin() {
         } 
       });
       alert('DEPLOYMENT COMPLETE');
-      setNewWeapon({ name: '', type: 'Standard', price: '', stock: '', description: '', image: null });
+      setNewWeapon({ name: '', type: 'standard', price: '', stock: '', description: '', image: null });
+      setNewSpecs({});
       setPanelOpen(false);
       fetchWeapons();
-    } catch (err) { alert('Deployment failed'); }
+    } catch (err) { alert(`Deployment failed${err.response?.data?.error ? `: ${err.response.data.error}` : ''}`); }
   };
 
   const handleDelete = async (id, name) => {
@@ -279,7 +317,7 @@ function Admin() {
           <div className="overflow-x-auto">
             <div className="min-w-[1100px] p-6 space-y-4">
               {weapons.map(w => (
-                <WeaponRow key={w.id} weapon={w} onEdit={(id, f, v) => setEditData(p => ({...p, [id]: {...p[id], [f]: v}}))} onUpdate={handleUpdateWeapon} onDelete={handleDelete} />
+                <WeaponRow key={w.id} weapon={w} categories={categories} onEdit={(id, f, v) => setEditData(p => ({...p, [id]: {...p[id], [f]: v}}))} onUpdate={handleUpdateWeapon} onDelete={handleDelete} />
               ))}
             </div>
           </div>
@@ -365,7 +403,7 @@ function Admin() {
             </FormField>
             
             <FormField label="Weapon Class">
-              <TacticalDropdown value={newWeapon.type} onChange={(val) => setNewWeapon({...newWeapon, type: val})} options={categories} />
+              <TacticalDropdown value={newWeapon.type} onChange={(val) => setNewWeapon({...newWeapon, type: val})} options={categories.slugs} labels={categories.labels} />
             </FormField>
             
             <div className="grid grid-cols-2 gap-6">
@@ -377,6 +415,34 @@ function Admin() {
               </FormField>
             </div>
             
+            {specAttrs.length > 0 && (
+              <div className="grid grid-cols-2 gap-6">
+                {specAttrs.map(a => (
+                  <FormField key={a.key} label={`${a.name}${a.unit ? ` (${a.unit})` : ''}${a.required ? ' *' : ''}`}>
+                    {a.type === 'enum' ? (
+                      <select className={INPUT_STYLE} value={newSpecs[a.key] ?? ''} onChange={e => setNewSpecs({...newSpecs, [a.key]: e.target.value})} required={a.required}>
+                        <option value="">—</option>
+                        {a.options.map(o => <option key={o} value={o}>{o}</option>)}
+                      </select>
+                    ) : a.type === 'boolean' ? (
+                      <input type="checkbox" checked={newSpecs[a.key] ?? false} onChange={e => setNewSpecs({...newSpecs, [a.key]: e.target.checked})} />
+                    ) : (
+                      <input
+                        type={a.type === 'text' ? 'text' : 'number'}
+                        step={a.type === 'integer' ? 1 : 'any'}
+                        min={a.min ?? undefined}
+                        max={a.max ?? undefined}
+                        className={INPUT_STYLE}
+                        value={newSpecs[a.key] ?? ''}
+                        onChange={e => setNewSpecs({...newSpecs, [a.key]: e.target.value})}
+                        required={a.required}
+                      />
+                    )}
+                  </FormField>
+                ))}
+              </div>
+            )}
+
             <FormField label="System Specs">
               <textarea className={`${INPUT_STYLE} h-32 resize-none`} value={newWeapon.description} onChange={e => setNewWeapon({...newWeapon, description: e.target.value})} placeholder="TECHNICAL DETAILS..." />
             </FormField>
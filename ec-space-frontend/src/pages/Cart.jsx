@@ -73,7 +73,7 @@ function Cart() {
     try {
       const orderData = {
         total: totalPrice,
-        items: cart.map(item => ({ weapon_id: item.id, quantity: item.quantity })),
+        items: cart.map(item => ({ weapon_id: item.id, variant_id: item.variant_id, quantity: item.quantity })),
       };
       const response = await api.post('/orders', orderData);
       if (response.status === 200) {
@@ -209,7 +209,7 @@ function Cart() {
               {cart.map((item, index) => {
                 const currentQty = Number(item.quantity) || 1;
                 return (
-                  <GradientBorder key={item.id} clip={CLIP_LG}>
+                  <GradientBorder key={item.variant_id} clip={CLIP_LG}>
                     {/* Inner card */}
                     <div
                       className="relative flex items-center gap-6 p-5 !bg-black/55 backdrop-blur-md border border-cyan-500/20 overflow-hidden"
@@ -222,7 +222,7 @@ function Cart() {
 
                       {/* UID tag */}
                       <span className="absolute top-2 right-3 font-mono text-[9px] text-slate-500 tracking-wider">
-                        UID:{String(item.id).padStart(4, '0')} // SLOT_{String(index + 1).padStart(2, '0')}
+                        SKU:{item.sku} // SLOT_{String(index + 1).padStart(2, '0')}
                       </span>
 
                       {/* Weapon image */}
@@ -249,6 +249,11 @@ function Cart() {
                         <h4 className="font-black text-xl text-white uppercase tracking-tight truncate">
                           {item.name}
                         </h4>
+                        {Object.keys(item.options || {}).length > 0 && (
+                          <p className="font-mono text-[10px] text-slate-400 mt-0.5 truncate">
+                            {Object.entries(item.options).map(([k, v]) => `${k.toUpperCase()}: ${v}`).join(' // ')}
+                          </p>
+                        )}
                         <p className="font-mono text-xs text-slate-300/85 mt-1">
                           UNIT:{' '}
                           <span className="chromatic text-cyan-300 font-bold">
@@ -261,8 +266,8 @@ function Cart() {
                       <div className="flex items-center gap-2 flex-shrink-0">
                         <QtyBtn
                           onClick={() => {
-                            if (currentQty - 1 >= 1) updateQuantity(item.id, currentQty - 1);
-                            else if (window.confirm('Remove this item?')) removeFromCart(item.id);
+                            if (currentQty - 1 >= 1) updateQuantity(item.variant_id, currentQty - 1);
+                            else if (window.confirm('Remove this item?')) removeFromCart(item.variant_id);
                           }}
                         >
                           −
@@ -270,7 +275,7 @@ function Cart() {
                         <div className="w-10 text-center font-mono font-black text-xl text-cyan-100 tabular-nums">
                           {currentQty}
                         </div>
-                        <QtyBtn onClick={() => updateQuantity(item.id, currentQty + 1)}>
+                        <QtyBtn onClick={() => updateQuantity(item.variant_id, currentQty + 1)}>
                           +
                         </QtyBtn>
                       </div>
@@ -0,0 +1,54 @@
+import { useState } from 'react';
+import { Link } from 'react-router-dom';
+import api from '../services/api';
+
+function ForgotPassword() {
+  const [email, setEmail] = useState('');
+  const [message, setMessage] = useState('');
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    try {
+      const res = await api.post('/password/forgot', { email });
+      setMessage(res.data.message);
+    } catch (error) {
+      setMessage(error.response?.data?.error || 'Transmission failed');
+    }
+  };
+
+  return (
+    <div className="min-h-[80vh] flex items-center justify-center px-4">
+      <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl">
+        <div className="text-center mb-10">
+          <h2 className="text-4xl font-black text-white italic tracking-tighter">LOST <span className="text-cyan-400">ACCESS</span></h2>
+          <p className="text-white/30 text-[9px] uppercase tracking-[0.3em] font-bold mt-2">We will transmit a reset link</p>
+        </div>
+
+        {message ? (
+          <p className="text-center text-cyan-300 text-sm">{message}</p>
+        ) : (
+          <form onSubmit={handleSubmit} className="space-y-6">
+            <div className="space-y-2">
+              <label className="text-[10px] font-black text-cyan-500 uppercase tracking-widest ml-1">Comm Channel</label>
+              <input
+                type="email"
+                className="w-full bg-white/5 border border-white/10 rounded-2xl px-5 py-4 text-white placeholder:text-white/10 outline-none focus:border-cyan-500/50 transition-all"
+                placeholder="ENTER EMAIL"
+                onChange={(e) => setEmail(e.target.value)}
+                required
+              />
+            </div>
+            <button type="submit" className="w-full bg-cyan-600 hover:bg-cyan-400 text-white font-black py-4 rounded-2xl transition-all shadow-lg shadow-cyan-900/40 uppercase tracking-widest text-xs mt-4">
+              Send Reset Link
+            </button>
+          </form>
+        )}
+        <p className="text-center mt-8 text-white/20 text-[10px] font-bold uppercase tracking-widest">
+          <Link to="/login" className="text-cyan-400 hover:text-white transition-all">Back to Login</Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default ForgotPassword;
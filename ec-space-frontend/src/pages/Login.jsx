@@ -1,26 +1,89 @@
-import { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
+import { useEffect, useState } from 'react';
+import { useNavigate, useLocation, Link } from 'react-router-dom';
 import api from '../services/api';
 
 function Login() {
-  const [username, setUsername] = useState('');
+  const location = useLocation();
+  // OidcCallback ส่ง challenge มาทาง state เมื่อบัญชีเปิด 2FA ไว้
+  const [username, setUsername] = useState(location.state?.username || '');
   const [password, setPassword] = useState('');
+  const [challenge, setChallenge] = useState(location.state?.challenge || null);
+  const [code, setCode] = useState('');
+  const [providers, setProviders] = useState([]);
   const navigate = useNavigate();
 
+  useEffect(() => {
+    api.get('/oidc/providers')
+      .then((res) => setProviders(res.data))
+      .catch(() => setProviders([]));
+  }, []);
+
+  const finishLogin = (data) => {
+    localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
+    localStorage.setItem('role', data.role);
+    localStorage.setItem('username', username);
+    navigate('/');
+    window.location.reload();
+  };
+
   const handleLogin = async (e) => {
     e.preventDefault();
     try {
       const res = await api.post('/login', { username, password });
-      localStorage.setItem('token', res.data.token);
-      localStorage.setItem('role', res.data.role);
-      localStorage.setItem('username', username);
-      navigate('/');
-      window.location.reload();
+      if (res.data.mfa_required) {
+        setChallenge(res.data.challenge_token);
+        return;
+      }
+      finishLogin(res.data);
     } catch (error) {
       alert('ACCESS DENIED: Credentials Invalid');
     }
   };
 
+  // รหัส 6 หลักจากแอป หรือ recovery code (xxxxx-xxxxx)
+  const handleTwoFactor = async (e) => {
+    e.preventDefault();
+    const payload = /^\d{6}$/.test(code.trim())
+      ? { challenge_token: challenge, code: code.trim() }
+      : { challenge_token: challenge, recovery_code: code.trim() };
+    try {
+      const res = await api.post('/login/2fa', payload);
+      finishLogin(res.data);
+    } catch (error) {
+      if (error.response?.status === 401 && /หมดอายุ/.test(error.response?.data?.error || '')) {
+        setChallenge(null);
+      }
+      alert(error.response?.data?.error || 'ACCESS DENIED: Code Invalid');
+    }
+  };
+
+  if (challenge) {
+    return (
+      <div className="min-h-[80vh] flex items-center justify-center px-4">
+        <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl">
+          <div className="text-center mb-10">
+            <h2 className="text-4xl font-black text-white italic tracking-tighter">SECOND <span className="text-cyan-400">FACTOR</span></h2>
+            <p className="text-white/30 text-[9px] uppercase tracking-[0.3em] font-bold mt-2">Enter the code from your authenticator or a recovery code</p>
+          </div>
+          <form onSubmit={handleTwoFactor} className="space-y-6">
+            <input
+              type="text"
+              autoComplete="one-time-code"
+              className="w-full bg-white/5 border border-white/10 rounded-2xl px-5 py-4 text-white text-center tracking-[0.5em] placeholder:text-white/10 outline-none focus:border-cyan-500/50 transition-all"
+              placeholder="000000"
+              onChange={(e) => setCode(e.target.value)}
+              required
+            />
+            <button type="submit" className="w-full bg-cyan-600 hover:bg-cyan-400 text-white font-black py-4 rounded-2xl transition-all shadow-lg shadow-cyan-900/40 uppercase tracking-widest text-xs mt-4">
+              Verify
+            </button>
+          </form>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="min-h-[80vh] flex items-center justify-center px-4">
       <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl">
@@ -54,7 +117,24 @@ function Login() {
             Authorize Access
           </button>
         </form>
-        <p className="text-center mt-8 text-white/20 text-[10px] font-bold uppercase tracking-widest">
+        {providers.length > 0 && (
+          <div className="mt-6 space-y-3">
+            <p className="text-center text-white/20 text-[9px] uppercase tracking-[0.3em] font-bold">Or continue with</p>
+            {providers.map((p) => (
+              <a
+                key={p.name}
+                href={`${api.defaults.baseURL}/oidc/${p.name}/login`}
+                className="block w-full text-center bg-white/5 border border-white/10 hover:border-cyan-500/50 text-white font-black py-3 rounded-2xl transition-all uppercase tracking-widest text-[10px]"
+              >
+                {p.display_name}
+              </a>
+            ))}
+          </div>
+        )}
+        <p className="text-center mt-6 text-[10px] font-bold uppercase tracking-widest">
+          <Link to="/forgot-password" className="text-white/40 hover:text-cyan-400 transition-all">Forgot Access Code?</Link>
+        </p>
+        <p className="text-center mt-4 text-white/20 text-[10px] font-bold uppercase tracking-widest">
           No Account? <Link to="/register" className="text-cyan-400 hover:text-white transition-all">Register Recruit</Link>
         </p>
       </div>
@@ -0,0 +1,57 @@
+import { useEffect, useState } from 'react';
+import { Link, useNavigate } from 'react-router-dom';
+
+const errorMessages = {
+  access_denied: 'ยกเลิกการเข้าสู่ระบบกับผู้ให้บริการแล้ว',
+  invalid_state: 'ลิงก์เข้าสู่ระบบหมดอายุหรือไม่ถูกต้อง กรุณาลองใหม่',
+  email_unverified: 'ผู้ให้บริการไม่ได้ยืนยันอีเมลของบัญชีนี้',
+  link_unverified: 'มีบัญชีที่ใช้อีเมลนี้อยู่แล้วแต่ยังไม่ได้ยืนยันอีเมล กรุณาเข้าสู่ระบบด้วยรหัสผ่านแล้วยืนยันอีเมลก่อน',
+  login_failed: 'เข้าสู่ระบบไม่สำเร็จ กรุณาลองใหม่',
+};
+
+// Backend ส่งผลลัพธ์มาใน URL fragment (#...) เพื่อไม่ให้ token ไปอยู่ใน log หรือ Referer
+function OidcCallback() {
+  const navigate = useNavigate();
+  const [status, setStatus] = useState('Signing in...');
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, '', window.location.pathname);
+
+    if (params.get('error')) {
+      setStatus(errorMessages[params.get('error')] || errorMessages.login_failed);
+      return;
+    }
+    if (params.get('mfa_required')) {
+      navigate('/login', {
+        replace: true,
+        state: { challenge: params.get('challenge_token'), username: params.get('username') },
+      });
+      return;
+    }
+    if (params.get('token')) {
+      localStorage.setItem('token', params.get('token'));
+      localStorage.setItem('refresh_token', params.get('refresh_token'));
+      localStorage.setItem('role', params.get('role'));
+      localStorage.setItem('username', params.get('username'));
+      navigate('/', { replace: true });
+      window.location.reload();
+      return;
+    }
+    setStatus(errorMessages.login_failed);
+  }, [navigate]);
+
+  return (
+    <div className="min-h-[80vh] flex items-center justify-center px-4">
+      <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl text-center">
+        <h2 className="text-4xl font-black text-white italic tracking-tighter mb-6">EXTERNAL <span className="text-cyan-400">LOGIN</span></h2>
+        <p className="text-cyan-300 text-sm">{status}</p>
+        <p className="mt-8 text-white/20 text-[10px] font-bold uppercase tracking-widest">
+          <Link to="/login" className="text-cyan-400 hover:text-white transition-all">Back to Login</Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default OidcCallback;
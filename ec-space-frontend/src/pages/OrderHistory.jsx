@@ -151,7 +151,7 @@ function OrderHistory() {
 
                         <div className="flex-1">
                           <p className="font-black text-lg text-white uppercase tracking-wide">{item.weapon?.name || "Unknown"}</p>
-                          <p className="text-sm text-slate-300/85 mt-1">{item.weapon?.type || "Standard"} • {item.quantity} pcs</p>
+                          <p className="text-sm text-slate-300/85 mt-1">{item.weapon?.category?.names?.en || item.weapon?.type || "Standard"} • {item.quantity} pcs</p>
                           <p className="text-sm text-slate-500 mt-2">Unit: {(item.weapon?.price || 0).toLocaleString()} Cr • Subtotal: {((item.weapon?.price || 0) * item.quantity).toLocaleString()} Cr</p>
                         </div>
 
@@ -1,6 +1,7 @@
 import { useEffect, useState } from 'react';
 import api from '../services/api';
 import { useNavigate } from 'react-router-dom';
+import ActiveSessions from '../components/ActiveSessions';
 
 const CLIP_XL = 'polygon(0 0, calc(100% - 22px) 0, 100% 22px, 100% 100%, 22px 100%, 0 100%)';
 const CLIP_SM = 'polygon(0 0, calc(100% - 8px) 0, 100% 8px, 100% 100%, 0 100%)';
@@ -197,6 +198,8 @@ export default function Profile() {
             </button>
           </div>
         </div>
+
+        <ActiveSessions />
       </div>
     </div>
   );
@@ -60,6 +60,7 @@ function Register() {
               className="w-full bg-white/5 border border-white/10 rounded-2xl px-5 py-3.5 text-white placeholder:text-white/10 outline-none focus:border-cyan-500/50 focus:bg-white/10 transition-all"
               placeholder="SECURE PASSWORD"
               onChange={(e) => setPassword(e.target.value)}
+              minLength={8}
               required
             />
           </div>
@@ -0,0 +1,53 @@
+import { useState } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import api from '../services/api';
+
+function ResetPassword() {
+  const [params] = useSearchParams();
+  const [password, setPassword] = useState('');
+  const [error, setError] = useState('');
+  const navigate = useNavigate();
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    try {
+      await api.post('/password/reset', { token: params.get('token'), password });
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      alert('Access code updated. Please log in again.');
+      navigate('/login');
+    } catch (err) {
+      setError(err.response?.data?.error || 'Reset failed');
+    }
+  };
+
+  return (
+    <div className="min-h-[80vh] flex items-center justify-center px-4">
+      <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl">
+        <div className="text-center mb-10">
+          <h2 className="text-4xl font-black text-white italic tracking-tighter">NEW <span className="text-cyan-400">ACCESS CODE</span></h2>
+        </div>
+
+        <form onSubmit={handleSubmit} className="space-y-6">
+          <div className="space-y-2">
+            <label className="text-[10px] font-black text-cyan-500 uppercase tracking-widest ml-1">Access Code</label>
+            <input
+              type="password"
+              minLength={8}
+              className="w-full bg-white/5 border border-white/10 rounded-2xl px-5 py-4 text-white placeholder:text-white/10 outline-none focus:border-cyan-500/50 transition-all"
+              placeholder="AT LEAST 8 CHARACTERS"
+              onChange={(e) => setPassword(e.target.value)}
+              required
+            />
+          </div>
+          {error && <p className="text-red-400 text-xs text-center">{error}</p>}
+          <button type="submit" className="w-full bg-cyan-600 hover:bg-cyan-400 text-white font-black py-4 rounded-2xl transition-all shadow-lg shadow-cyan-900/40 uppercase tracking-widest text-xs mt-4">
+            Update Access Code
+          </button>
+        </form>
+      </div>
+    </div>
+  );
+}
+
+export default ResetPassword;
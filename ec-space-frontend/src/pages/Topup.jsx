@@ -200,9 +200,10 @@ function Topup() {
     setStatusMsg('PROCESSING TRANSFER — AWAITING CONFIRMATION...');
     try {
       const token = localStorage.getItem('token');
-      await api.post('/topup', { amount }, {
-        headers: { Authorization: `Bearer ${token}` },
-      });
+      const headers = { Authorization: `Bearer ${token}`, 'Idempotency-Key': `${txId}-${amount}` };
+      const { data: intent } = await api.post('/topup/intents', { amount }, { headers });
+      const { status, data } = await api.post(`/topup/intents/${intent.id}/confirm`, null, { headers });
+      if (status !== 200) throw { response: { data: { error: data?.message || 'PAYMENT PENDING' } } };
       clearInterval(timerRef.current);
       setTxStatus('success');
       setStatusMsg(`+${amount.toLocaleString()} CR DEPOSITED SUCCESSFULLY`);
@@ -0,0 +1,28 @@
+import { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import api from '../services/api';
+
+function VerifyEmail() {
+  const [params] = useSearchParams();
+  const [status, setStatus] = useState('Verifying...');
+
+  useEffect(() => {
+    api.post('/email/verify', { token: params.get('token') })
+      .then((res) => setStatus(res.data.message))
+      .catch((err) => setStatus(err.response?.data?.error || 'Verification failed'));
+  }, [params]);
+
+  return (
+    <div className="min-h-[80vh] flex items-center justify-center px-4">
+      <div className="w-full max-w-md bg-black/40 backdrop-blur-2xl border border-white/10 p-10 rounded-[40px] shadow-2xl text-center">
+        <h2 className="text-4xl font-black text-white italic tracking-tighter mb-6">EMAIL <span className="text-cyan-400">VERIFICATION</span></h2>
+        <p className="text-cyan-300 text-sm">{status}</p>
+        <p className="mt-8 text-white/20 text-[10px] font-bold uppercase tracking-widest">
+          <Link to="/" className="text-cyan-400 hover:text-white transition-all">Return to Base</Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default VerifyEmail;
@@ -7,19 +7,33 @@ function WeaponDetail() {
   const { id } = useParams();
   const [weapon, setWeapon] = useState(null);
   const [quantity, setQuantity] = useState(1);
+  // ตัวเลือกที่เลือกอยู่ เช่น { color: 'black', capacity: '200' }
+  const [selected, setSelected] = useState({});
   const { addToCart } = useCart();
   const navigate = useNavigate();
 
   useEffect(() => {
-    api.get(`/weapons`)
+    api.get(`/weapons/${id}`)
       .then(res => {
-        const found = res.data.find(w => String(w.id) === String(id));
-        if (found) setWeapon(found);
-        else navigate(-1);
+        setWeapon(res.data);
+        // เริ่มจากรุ่นแรกที่ยังมีของ
+        const variants = res.data.variants || [];
+        const first = variants.find(v => v.stock > 0) || variants[0];
+        setSelected(first ? { ...first.options } : {});
       })
-      .catch(err => console.error("Error fetching weapons:", err));
+      .catch(err => {
+        console.error("Error fetching weapon:", err);
+        navigate(-1);
+      });
   }, [id, navigate]);
 
+  const variants = weapon?.variants || [];
+  const variant = variants.find(v =>
+    Object.entries(v.options || {}).every(([k, val]) => selected[k] === val)
+  );
+  const price = variant ? variant.unit_price : weapon?.price;
+  const stock = variant ? variant.stock : 0;
+
   if (!weapon) return (
     <div className="min-h-screen flex items-center justify-center bg-transparent">
       <div className="text-cyan-400 font-mono text-2xl animate-pulse tracking-tighter">
@@ -45,14 +59,52 @@ function WeaponDetail() {
           
           {/* ✅ เปลี่ยนจาก slate-400 เป็น slate-300 เพื่อให้อ่านออกบนพื้นหลังดำ */}
           <p className="text-slate-300 text-lg mb-8 leading-relaxed">{weapon.description}</p>
+
+          {/* สเปกทางเทคนิค */}
+          {(weapon.spec_sheet || []).length > 0 && (
+            <div className="mb-8 grid grid-cols-2 gap-x-6 gap-y-2 bg-white/5 p-6 rounded-3xl border border-white/5">
+              {weapon.spec_sheet.map(s => (
+                <div key={s.key} className="flex justify-between gap-4 border-b border-white/5 pb-1">
+                  <span className="text-xs text-slate-400 uppercase tracking-widest">{s.name}</span>
+                  <span className="font-mono text-sm text-white">
+                    {s.type === 'boolean' ? (s.value ? '✓' : '✗') : String(s.value)}{s.unit ? ` ${s.unit}` : ''}
+                  </span>
+                </div>
+              ))}
+            </div>
+          )}
+
+          {/* ตัวเลือกรุ่น (variant) */}
+          {(weapon.options || []).map(opt => (
+            <div key={opt.name} className="mb-6">
+              <div className="text-xs text-slate-300 font-bold uppercase tracking-widest mb-2">{opt.name}</div>
+              <div className="flex flex-wrap gap-2">
+                {opt.values.map(value => {
+                  const active = selected[opt.name] === value;
+                  return (
+                    <button
+                      key={value}
+                      onClick={() => { setSelected(s => ({ ...s, [opt.name]: value })); setQuantity(1); }}
+                      className={`px-4 py-2 rounded-xl font-mono text-sm uppercase border-2 transition-all ${active ? '!border-cyan-400 !text-white !bg-cyan-500/20' : '!border-white/10 !text-slate-300 !bg-transparent hover:!border-cyan-500/50'}`}
+                    >
+                      {value}
+                    </button>
+                  );
+                })}
+              </div>
+            </div>
+          ))}
           
           <div className="bg-white/5 p-8 rounded-3xl border border-white/5 mb-8 flex flex-col md:flex-row items-center justify-between gap-6">
             <div>
               <span className="text-5xl font-mono text-cyan-400 font-black tracking-tighter">
-                {weapon.price?.toLocaleString()} <span className="text-xl">CR</span>
+                {price?.toLocaleString()} <span className="text-xl">CR</span>
               </span>
               {/* ✅ เปลี่ยนจาก slate-500 เป็น slate-300 ให้เห็นสต็อกชัดๆ */}
-              <div className="text-sm text-slate-300 mt-2 font-bold uppercase tracking-widest">Available Stock: {weapon.stock ?? 'N/A'}</div>
+              <div className="text-sm text-slate-300 mt-2 font-bold uppercase tracking-widest">
+                {variant ? `Available Stock: ${stock}` : 'รุ่นนี้ไม่มีจำหน่าย'}
+              </div>
+              {variant && <div className="text-[10px] text-slate-500 mt-1 font-mono tracking-widest">SKU: {variant.sku}</div>}
             </div>
             
             <div className="flex items-center gap-4 bg-black/40 p-2 rounded-2xl border border-white/5">
@@ -78,7 +130,7 @@ function WeaponDetail() {
 
   {/* ➕ ปุ่มเพิ่มจำนวน (Plus) */}
   <button
-    onClick={() => setQuantity(q => Math.min(weapon.stock || 99, q + 1))}
+    onClick={() => setQuantity(q => Math.min(stock || 1, q + 1))}
     style={{ 
       color: 'white',
       backgroundColor: 'transparent',
@@ -100,9 +152,10 @@ function WeaponDetail() {
     if (!weapon || !weapon.id) return;
     const token = localStorage.getItem('token');
     if (!token) return navigate('/login');
+    if (!variant) return alert('กรุณาเลือกรุ่นของสินค้า');
 
     try {
-      await addToCart({ ...weapon, id: Number(weapon.id) }, Number(quantity));
+      await addToCart({ ...weapon, id: Number(weapon.id) }, Number(quantity), variant);
       alert("Added to cart!"); // เพิ่มแจ้งเตือนให้ผู้ใช้รู้ว่าสำเร็จ
     } catch (err) {
       alert(err.message);
@@ -1,30 +1,131 @@
 import { useEffect, useState } from 'react';
 import { Link } from 'react-router-dom';
 import api from '../services/api';
-import categories from '../constants/categories';
 import { useLanguage } from '../contexts/LanguageContext';
 
+const PAGE_SIZE = 24;
+
+const sorts = [
+  { value: 'newest', label: 'Newest' },
+  { value: 'price', label: 'Price: Low to High' },
+  { value: '-price', label: 'Price: High to Low' },
+  { value: '-power', label: 'Power' },
+  { value: 'name', label: 'Name' },
+];
+
 function WeaponList() {
   const [weapons, setWeapons] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [facets, setFacets] = useState([]);
+  const [inStockTotal, setInStockTotal] = useState(null);
+  const [page, setPage] = useState(1);
+  const [categories, setCategories] = useState([]);
   const [selectedCategory, setSelectedCategory] = useState('All');
+  const [search, setSearch] = useState('');
+  const [query, setQuery] = useState('');
+  const [suggestions, setSuggestions] = useState([]);
+  const [sort, setSort] = useState('newest');
+  const [inStockOnly, setInStockOnly] = useState(false);
+  // spec ที่กรองได้ของหมวดหมู่ที่เลือก และค่าที่กรองอยู่ เช่น { range_m: { min: '100' } }
+  const [specAttrs, setSpecAttrs] = useState([]);
+  const [specFilters, setSpecFilters] = useState({});
   const { t } = useLanguage();
 
+  // รอให้พิมพ์เสร็จก่อนค่อยค้นหา
+  useEffect(() => {
+    const timer = setTimeout(() => setQuery(search.trim()), 300);
+    return () => clearTimeout(timer);
+  }, [search]);
+
+  // คำแนะนำระหว่างพิมพ์ (พิมพ์ผิดเล็กน้อยก็ยังเจอ)
+  useEffect(() => {
+    const text = search.trim();
+    if (text.length < 2) {
+      setSuggestions([]);
+      return;
+    }
+    const timer = setTimeout(() => {
+      api.get('/weapons/suggest', { params: { q: text } })
+        .then(res => setSuggestions(res.data))
+        .catch(() => setSuggestions([]));
+    }, 150);
+    return () => clearTimeout(timer);
+  }, [search]);
+
+  useEffect(() => {
+    setPage(1);
+  }, [selectedCategory, query, sort, inStockOnly, specFilters]);
+
+  // เปลี่ยนหมวดหมู่แล้วโหลด spec ที่กรองได้ใหม่
+  useEffect(() => {
+    setSpecFilters(prev => (Object.keys(prev).length ? {} : prev));
+    const category = categories.find(c => c.slug === selectedCategory);
+    if (!category) {
+      setSpecAttrs([]);
+      return;
+    }
+    api.get(`/categories/${category.id}/specs`)
+      .then(res => setSpecAttrs(res.data.filter(a => a.filterable && a.type !== 'text')))
+      .catch(() => setSpecAttrs([]));
+  }, [selectedCategory, categories]);
+
+  const setSpecFilter = (key, field, value) =>
+    setSpecFilters(prev => ({ ...prev, [key]: { ...prev[key], [field]: value } }));
+
   useEffect(() => {
     const fetchWeapons = async () => {
       try {
-        const response = await api.get('/weapons');
-        setWeapons(response.data);
+        const params = { page, page_size: PAGE_SIZE, sort };
+        if (selectedCategory !== 'All') params.type = selectedCategory;
+        if (query) params.q = query;
+        if (inStockOnly) params.in_stock = true;
+        Object.entries(specFilters).forEach(([key, f]) => {
+          if (f.value) params[`spec[${key}]`] = f.value;
+          if (f.min !== undefined && f.min !== '') params[`spec_min[${key}]`] = f.min;
+          if (f.max !== undefined && f.max !== '') params[`spec_max[${key}]`] = f.max;
+        });
+        const response = await api.get('/weapons', { params });
+        setWeapons(prev => (page === 1 ? response.data.items : [...prev, ...response.data.items]));
+        setTotal(response.data.total);
+        setFacets(response.data.facets.type);
       } catch (error) {
         console.error("ดึงข้อมูลไม่สำเร็จ:", error);
       }
     };
     fetchWeapons();
+  }, [page, selectedCategory, query, sort, inStockOnly, specFilters]);
+
+  useEffect(() => {
+    api.get('/weapons', { params: { in_stock: true, page_size: 1 } })
+      .then(res => setInStockTotal(res.data.total))
+      .catch(() => setInStockTotal(null));
   }, []);
 
-  // กรองอาวุธตามหมวดหมู่ที่เลือก
-  const filteredWeapons = weapons.filter(weapon => 
-    selectedCategory === 'All' ? true : weapon.type === selectedCategory
-  );
+  useEffect(() => {
+    api.get('/categories')
+      .then(res => setCategories(res.data))
+      .catch(() => setCategories([]));
+  }, []);
+
+  // หมวดหมู่แม่นับรวมอาวุธในหมวดหมู่ย่อยด้วย (เหมือนตัวกรองฝั่ง server)
+  const facetCount = (slug) => {
+    if (slug === 'All') return facets.reduce((sum, f) => sum + f.count, 0);
+    const slugs = new Set([slug]);
+    let grew = true;
+    while (grew) {
+      grew = false;
+      categories.forEach(c => {
+        const parent = categories.find(p => p.id === c.parent_id);
+        if (parent && slugs.has(parent.slug) && !slugs.has(c.slug)) {
+          slugs.add(c.slug);
+          grew = true;
+        }
+      });
+    }
+    return facets.filter(f => slugs.has(f.value)).reduce((sum, f) => sum + f.count, 0);
+  };
+
+  const categoryName = (slug) => categories.find(c => c.slug === slug)?.name ?? slug;
 
   return (
     <div className="min-h-screen bg-transparent">
@@ -60,9 +161,9 @@ function WeaponList() {
             {/* Stats row */}
             <div className="flex flex-wrap gap-6 md:gap-12 mb-8">
               {[
-                { label: t('Weapons'), value: weapons.length > 0 ? weapons.length : '—' },
-                { label: t('Categories'), value: '7' },
-                { label: t('In Stock'), value: weapons.length > 0 ? weapons.filter(w => w.stock > 0).length : '—' },
+                { label: t('Weapons'), value: total > 0 ? total : '—' },
+                { label: t('Categories'), value: categories.length || '—' },
+                { label: t('In Stock'), value: inStockTotal ?? '—' },
               ].map((stat) => (
                 <div key={stat.label} className="flex flex-col">
                   <span className="text-2xl font-black text-cyan-400 font-mono italic">{stat.value}</span>
@@ -103,26 +204,104 @@ function WeaponList() {
       </h1>
 
       {/* --- ส่วนแสดงหมวดหมู่ (Category Bar) --- */}
-      <div className="flex flex-wrap gap-3 mb-10">
-        {categories.map((cat) => (
+      <div className="flex flex-wrap gap-3 mb-6">
+        {[{ slug: 'All', name: 'All', depth: 0 }, ...categories].map((cat) => (
           <button
-            key={cat}
-            onClick={() => setSelectedCategory(cat)}
+            key={cat.slug}
+            onClick={() => setSelectedCategory(cat.slug)}
             className={`px-6 py-2 rounded-full font-white text-xs uppercase tracking-widest transition-all
-              ${selectedCategory === cat 
+              ${selectedCategory === cat.slug 
                 ? 'bg-cyan-500 !text-white shadow-[0_0_20px_rgba(6,182,212,0.6)] border-2 border-cyan-300' 
                 : 'bg-white/5 !text-white/50 border border-white/10 hover:bg-white/10 hover:!text-white'
               }`}
           >
-            {t(cat)}
+            {cat.depth > 0 && '↳ '}{t(cat.name)} <span className="opacity-60">({facetCount(cat.slug)})</span>
           </button>
         ))}
       </div>
 
+      {/* --- ค้นหา / เรียงลำดับ --- */}
+      <div className="flex flex-wrap items-center gap-4 mb-10">
+        <input
+          type="search"
+          value={search}
+          onChange={(e) => setSearch(e.target.value)}
+          placeholder={t('Search weapons...')}
+          list="weapon-suggestions"
+          className="flex-1 min-w-[220px] px-5 py-2 rounded-full bg-white/5 border border-white/10 text-white text-sm focus:outline-none focus:border-cyan-500/60"
+        />
+        <datalist id="weapon-suggestions">
+          {suggestions.map((w) => (
+            <option key={w.id} value={w.name}>{t(categoryName(w.type))}</option>
+          ))}
+        </datalist>
+        <select
+          value={sort}
+          onChange={(e) => setSort(e.target.value)}
+          className="px-4 py-2 rounded-full bg-black/60 border border-white/10 text-white text-xs uppercase tracking-widest"
+        >
+          {sorts.map((s) => (
+            <option key={s.value} value={s.value}>{t(s.label)}</option>
+          ))}
+        </select>
+        <label className="flex items-center gap-2 text-xs text-white/60 uppercase tracking-widest">
+          <input type="checkbox" checked={inStockOnly} onChange={(e) => setInStockOnly(e.target.checked)} />
+          {t('In stock only')}
+        </label>
+      </div>
+
+      {/* --- กรองตาม spec ของหมวดหมู่ที่เลือก --- */}
+      {specAttrs.length > 0 && (
+        <div className="flex flex-wrap items-end gap-4 -mt-6 mb-10">
+          {specAttrs.map((a) => (
+            <div key={a.key} className="flex flex-col gap-1">
+              <span className="text-[10px] text-white/50 uppercase tracking-widest">
+                {a.name}{a.unit ? ` (${a.unit})` : ''}
+              </span>
+              {a.type === 'enum' && (
+                <select
+                  value={specFilters[a.key]?.value ?? ''}
+                  onChange={(e) => setSpecFilter(a.key, 'value', e.target.value)}
+                  className="px-3 py-1.5 rounded-full bg-black/60 border border-white/10 text-white text-xs"
+                >
+                  <option value="">{t('All')}</option>
+                  {a.options.map((o) => <option key={o} value={o}>{o}</option>)}
+                </select>
+              )}
+              {a.type === 'boolean' && (
+                <select
+                  value={specFilters[a.key]?.value ?? ''}
+                  onChange={(e) => setSpecFilter(a.key, 'value', e.target.value)}
+                  className="px-3 py-1.5 rounded-full bg-black/60 border border-white/10 text-white text-xs"
+                >
+                  <option value="">{t('All')}</option>
+                  <option value="true">✓</option>
+                  <option value="false">✗</option>
+                </select>
+              )}
+              {(a.type === 'number' || a.type === 'integer') && (
+                <div className="flex items-center gap-1">
+                  {['min', 'max'].map((field) => (
+                    <input
+                      key={field}
+                      type="number"
+                      placeholder={field}
+                      value={specFilters[a.key]?.[field] ?? ''}
+                      onChange={(e) => setSpecFilter(a.key, field, e.target.value)}
+                      className="w-20 px-3 py-1.5 rounded-full bg-white/5 border border-white/10 text-white text-xs"
+                    />
+                  ))}
+                </div>
+              )}
+            </div>
+          ))}
+        </div>
+      )}
+
       {/* --- ส่วนแสดงรายการอาวุธที่ถูกกรองแล้ว --- */}
       <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-8">
-        {filteredWeapons.length > 0 ? (
-          filteredWeapons.map((weapon) => {
+        {weapons.length > 0 ? (
+          weapons.map((weapon) => {
             const isOutOfStock = weapon.stock <= 0; 
 
             return (
@@ -150,7 +329,7 @@ function WeaponList() {
                   <div className="p-6 flex-1 flex flex-col">
                     <div className="flex justify-between items-start mb-2">
                       <div>
-                        <p className="text-[10px] text-cyan-400 font-black uppercase mb-1">{t(weapon.type)}</p>
+                        <p className="text-[10px] text-cyan-400 font-black uppercase mb-1">{t(weapon.category?.names?.en ?? weapon.type)}</p>
                         <h3 className="text-xl font-bold text-white uppercase">{t(weapon.name)}</h3>
                       </div>
                       <span className={`text-[10px] font-black px-2 py-1 rounded ${isOutOfStock ? 'bg-red-500/20 text-red-500' : 'bg-cyan-500/20 text-cyan-400'}`}>
@@ -176,6 +355,17 @@ function WeaponList() {
           </div>
         )}
       </div>
+
+      {weapons.length < total && (
+        <div className="flex justify-center mt-12">
+          <button
+            onClick={() => setPage(p => p + 1)}
+            className="px-8 py-3 rounded-full bg-white/5 border border-cyan-500/40 !text-cyan-300 font-black uppercase tracking-widest text-xs hover:bg-cyan-500/20 transition-all"
+          >
+            {t('Load more')} ({weapons.length}/{total})
+          </button>
+        </div>
+      )}
       </div> {/* end #inventory */}
     </div>
   );
@@ -13,4 +13,36 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
-export default api;
\ No newline at end of file
+// Access token อายุสั้น: ถ้าโดน 401 ให้ขอ token ใหม่ด้วย refresh token แล้วยิงซ้ำครั้งเดียว
+let refreshing = null;
+
+api.interceptors.response.use(
+  (res) => res,
+  async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem('refresh_token');
+    const isAuthCall = original?.url?.startsWith('/token/refresh') || original?.url?.startsWith('/login');
+
+    if (error.response?.status !== 401 || !refreshToken || original._retried || isAuthCall) {
+      return Promise.reject(error);
+    }
+    original._retried = true;
+
+    try {
+      refreshing = refreshing || api.post('/token/refresh', { refresh_token: refreshToken });
+      const { data } = await refreshing;
+      localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
+      original.headers.Authorization = `Bearer ${data.token}`;
+      return api(original);
+    } catch (refreshError) {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      return Promise.reject(refreshError);
+    } finally {
+      refreshing = null;
+    }
+  },
+);
+
+export default api;
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/mail"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errUserTokenInvalid = errors.New("token is invalid, used or expired")
+
+// userTokenCooldown is the minimum gap between two emails of the same kind
+// to one user, so the forgot/resend endpoints can't be used to spam inboxes.
+const userTokenCooldown = time.Minute
+
+// issueUserTokenTx creates a single-use token for user and invalidates any
+// earlier unused token with the same purpose. It returns the plain token to
+// put in the email.
+func issueUserTokenTx(tx *gorm.DB, user models.User, purpose string, ttl time.Duration) (string, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+		Update("used_at", time.Now()).Error; err != nil {
+		return "", err
+	}
+	err = tx.Create(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}).Error
+	return plain, err
+}
+
+// consumeUserTokenTx looks up a token by its plain value, checks that it is
+// unused and unexpired, and marks it used.
+func consumeUserTokenTx(tx *gorm.DB, plain, purpose string) (models.UserToken, error) {
+	var t models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", auth.HashToken(plain), purpose).
+		First(&t).Error; err != nil {
+		return t, errUserTokenInvalid
+	}
+	if t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+		return t, errUserTokenInvalid
+	}
+	if err := tx.Model(&t).Update("used_at", time.Now()).Error; err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// recentUserToken reports whether an email of this kind went out to the user
+// within userTokenCooldown.
+func recentUserToken(userID uint, purpose string) bool {
+	var n int64
+	config.DB.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-userTokenCooldown)).
+		Count(&n)
+	return n > 0
+}
+
+// sendMail delivers msg in the background so request latency does not
+// depend on the mail server, or reveal whether an account exists.
+func sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := mail.Default().Send(ctx, msg); err != nil {
+			log.Printf("[MAIL] send %q to %s failed: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// frontendLink builds a link to a frontend page carrying token.
+func frontendLink(path, token string) string {
+	return strings.TrimRight(config.App.Server.FrontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail issues a verification token for user's current email
+// and mails the link.
+func sendVerificationEmail(user models.User) error {
+	ttl := config.App.Auth.EmailVerifyTTL.Duration
+	var plain string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		plain, err = issueUserTokenTx(tx, user, models.TokenPurposeEmailVerify, ttl)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "ยืนยันอีเมลของคุณ — EC-Space",
+		Body: fmt.Sprintf("สวัสดี %s\n\nกรุณายืนยันอีเมลโดยเปิดลิงก์นี้ภายใน %s:\n%s\n\nหากคุณไม่ได้สมัครสมาชิก ไม่ต้องทำอะไร\n",
+			user.Username, ttl, frontendLink("/verify-email", plain)),
+	})
+	return nil
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the email belongs to an account.
+func ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณากรอกอีเมลให้ถูกต้อง"})
+		return
+	}
+	generic := gin.H{"message": "หากอีเมลนี้มีบัญชีอยู่ เราได้ส่งลิงก์สำหรับตั้งรหัสผ่านใหม่ไปแล้ว"}
+
+	var user models.User
+	if err := config.DB.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(input.Email)).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, generic)
+		return
+	}
+	if recentUserToken(user.ID, models.TokenPurposePasswordReset) {
+		c.JSON(http.StatusOK, generic)
+		return
+	}
+
+	ttl := config.App.Auth.PasswordResetTTL.Duration
+	var plain string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		plain, err = issueUserTokenTx(tx, user, models.TokenPurposePasswordReset, ttl)
+		return err
+	})
+	if err != nil {
+		log.Printf("[AUTH] password reset token failed (uid=%d): %v", user.ID, err)
+		c.JSON(http.StatusOK, generic)
+		return
+	}
+
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "ตั้งรหัสผ่านใหม่ — EC-Space",
+		Body: fmt.Sprintf("สวัสดี %s\n\nมีคำขอตั้งรหัสผ่านใหม่สำหรับบัญชีของคุณ เปิดลิงก์นี้ภายใน %s:\n%s\n\nลิงก์ใช้ได้ครั้งเดียว หากคุณไม่ได้ขอ ไม่ต้องทำอะไร\n",
+			user.Username, ttl, frontendLink("/reset-password", plain)),
+	})
+	log.Printf("[AUTH] password reset requested (uid=%d)", user.ID)
+	c.JSON(http.StatusOK, generic)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword and
+// logs the account out everywhere, revoking its API keys as well.
+func ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาส่ง token และรหัสผ่านใหม่"})
+		return
+	}
+	if len(input.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผ่านต้องมีอย่างน้อย 8 ตัวอักษร"})
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ตั้งรหัสผ่านไม่สำเร็จ"})
+		return
+	}
+
+	var user models.User
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := consumeUserTokenTx(tx, input.Token, models.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, t.UserID).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Email, t.Email) {
+			return errUserTokenInvalid
+		}
+
+		updates := map[string]interface{}{"password": string(hashed)}
+		// The link reached this inbox, so the address is proven too.
+		if !user.Verified {
+			updates["verified"] = true
+			updates["verified_at"] = time.Now()
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		// A locked-out owner can get back in by resetting their password.
+		if err := clearLoginFailures(tx, user.Username); err != nil {
+			return err
+		}
+		if err := revokeUserAPIKeys(tx, user.ID); err != nil {
+			return err
+		}
+		return revokeUserFamilies(tx, user.ID, "password reset")
+	})
+
+	if errors.Is(err, errUserTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ลิงก์ไม่ถูกต้อง ถูกใช้ไปแล้ว หรือหมดอายุ"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] password reset failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ตั้งรหัสผ่านไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[AUTH] password reset (uid=%d)", user.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "ตั้งรหัสผ่านใหม่เรียบร้อย กรุณาเข้าสู่ระบบอีกครั้ง"})
+}
+
+// VerifyEmail marks the user's email as verified using the emailed token.
+func VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาส่ง token"})
+		return
+	}
+
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := consumeUserTokenTx(tx, input.Token, models.TokenPurposeEmailVerify)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, t.UserID).Error; err != nil {
+			return err
+		}
+		// The user changed their email after this link was sent.
+		if !strings.EqualFold(user.Email, t.Email) {
+			return errUserTokenInvalid
+		}
+		if user.Verified {
+			return nil
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{"verified": true, "verified_at": time.Now()}).Error
+	})
+
+	if errors.Is(err, errUserTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ลิงก์ไม่ถูกต้อง ถูกใช้ไปแล้ว หรือหมดอายุ"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] email verify failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยืนยันอีเมลไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[AUTH] email verified (uid=%d)", user.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "ยืนยันอีเมลเรียบร้อย", "email": user.Email, "verified": true})
+}
+
+// ResendVerification mails a fresh verification link to the logged-in user.
+func ResendVerification(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+	if user.Verified {
+		c.JSON(http.StatusOK, gin.H{"message": "อีเมลนี้ยืนยันแล้ว", "verified": true})
+		return
+	}
+	if recentUserToken(user.ID, models.TokenPurposeEmailVerify) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "เพิ่งส่งลิงก์ไปแล้ว กรุณารอสักครู่แล้วลองใหม่"})
+		return
+	}
+	if err := sendVerificationEmail(user); err != nil {
+		log.Printf("[AUTH] verification email failed (uid=%d): %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ส่งอีเมลยืนยันไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ส่งลิงก์ยืนยันไปที่ " + user.Email + " แล้ว"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"path/filepath"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/Bannawat01/ec-space/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // AddWeapon - Admin creates new weapon
@@ -25,23 +27,80 @@ func AddWeapon(c *gin.Context) {
 		return
 	}
 
+	parsedPrice, err := models.ParseMoney(price)
+	if err != nil || parsedPrice < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ราคาไม่ถูกต้อง"})
+		return
+	}
+	parsedStock := utils.ToInt(stock)
+	if parsedStock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "สต็อกต้องไม่ติดลบ"})
+		return
+	}
+	if ok, err := categoryExists(weaponType); err != nil || !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบหมวดหมู่ " + weaponType})
+		return
+	}
+	specs, ok := weaponSpecs(c, weaponType, nil)
+	if !ok {
+		return
+	}
+
 	newFileName := uuid.New().String() + filepath.Ext(file.Filename)
 	imagePath := "uploads/" + newFileName
-	c.SaveUploadedFile(file, imagePath)
+	c.SaveUploadedFile(file, filepath.Join(config.App.Server.UploadDir, newFileName))
 
 	newWeapon := models.Weapon{
 		Name:        name,
 		Type:        weaponType,
-		Price:       utils.ToFloat64(price),
-		Stock:       utils.ToInt(stock),
+		Price:       parsedPrice,
+		Stock:       parsedStock,
 		Description: description,
 		ImageURL:    imagePath,
+		Specs:       specs,
 	}
 
-	config.DB.Create(&newWeapon)
+	// Every weapon starts with one variant that holds its stock.
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newWeapon).Error; err != nil {
+			return err
+		}
+		variant := defaultVariant(newWeapon, newWeapon.Stock)
+		return tx.Create(&variant).Error
+	})
+	if err != nil {
+		log.Printf("[ADMIN] add weapon failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มอาวุธไม่สำเร็จ"})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{"message": "เพิ่มอาวุธสำเร็จ!"})
 }
 
+// weaponSpecs applies the specs form field (a JSON object, null values
+// remove a spec) to current and validates the result against the schema of
+// the category. It responds with 400 and returns false when that fails.
+func weaponSpecs(c *gin.Context, category string, current models.Specs) (models.Specs, bool) {
+	input := map[string]interface{}{}
+	if raw := c.PostForm("specs"); raw != "" {
+		var err error
+		if input, err = decodeSpecs(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+	}
+	schema, err := categorySchema(config.DB, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ตรวจสอบ spec ไม่สำเร็จ"})
+		return nil, false
+	}
+	specs, err := applySpecs(schema, current, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return specs, true
+}
+
 // UpdateWeapon - Admin updates weapon
 func UpdateWeapon(c *gin.Context) {
 	id := c.Param("id")
@@ -56,41 +115,126 @@ func UpdateWeapon(c *gin.Context) {
 		weapon.Name = v
 	}
 	if v := c.PostForm("price"); v != "" {
-		weapon.Price = utils.ToFloat64(v)
+		p, err := models.ParseMoney(v)
+		if err != nil || p < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ราคาไม่ถูกต้อง"})
+			return
+		}
+		weapon.Price = p
 	}
+	// stock is a shortcut for weapons with a single variant; with more than
+	// one, each variant's stock is set on its own.
+	var stockVariant *models.WeaponVariant
 	if v := c.PostForm("stock"); v != "" {
-		weapon.Stock = utils.ToInt(v)
+		var variants []models.WeaponVariant
+		if err := config.DB.Where("weapon_id = ?", weapon.ID).Find(&variants).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตไม่สำเร็จ: " + err.Error()})
+			return
+		}
+		if len(variants) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "อาวุธนี้มีหลายรุ่น กรุณาแก้สต็อกที่แต่ละรุ่น"})
+			return
+		}
+		stockVariant = &variants[0]
+		if stockVariant.Stock = utils.ToInt(v); stockVariant.Stock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "สต็อกต้องไม่ติดลบ"})
+			return
+		}
 	}
 	if v := c.PostForm("description"); v != "" {
 		weapon.Description = v
 	}
 	if v := c.PostForm("type"); v != "" {
+		if ok, err := categoryExists(v); err != nil || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบหมวดหมู่ " + v})
+			return
+		}
 		weapon.Type = v
 	}
+	if _, sent := c.GetPostForm("specs"); sent || c.PostForm("type") != "" {
+		specs, ok := weaponSpecs(c, weapon.Type, weapon.Specs)
+		if !ok {
+			return
+		}
+		weapon.Specs = specs
+	}
 
 	file, err := c.FormFile("image")
 	if err == nil {
 		newFileName := uuid.New().String() + filepath.Ext(file.Filename)
 		imagePath := "uploads/" + newFileName
-		c.SaveUploadedFile(file, imagePath)
+		c.SaveUploadedFile(file, filepath.Join(config.App.Server.UploadDir, newFileName))
 		weapon.ImageURL = imagePath
 	}
 
-	config.DB.Save(&weapon)
+	// weapons.stock follows the variants (see models.WeaponVariant).
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("stock").Save(&weapon).Error; err != nil {
+			return err
+		}
+		if stockVariant != nil {
+			return tx.Model(stockVariant).Update("stock", stockVariant.Stock).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตไม่สำเร็จ: " + err.Error()})
+		return
+	}
 	c.JSON(200, gin.H{"message": "อัปเดตสำเร็จ!"})
 }
 
-// DeleteWeapon - Admin deletes weapon
+// DeleteWeapon - Admin archives weapon (soft delete, order history is kept)
 func DeleteWeapon(c *gin.Context) {
 	id := c.Param("id")
 
-	// ลบ records ที่อ้างอิง weapon นี้ก่อน
-	config.DB.Where("weapon_id = ?", id).Delete(&models.CartItem{})
-	config.DB.Where("weapon_id = ?", id).Delete(&models.OrderItem{})
+	var weapon models.Weapon
+	if err := config.DB.First(&weapon, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอาวุธ"})
+		return
+	}
 
-	if err := config.DB.Delete(&models.Weapon{}, id).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// เอาออกจากตะกร้าของทุกคน แต่ไม่แตะ order_items เพื่อเก็บประวัติการสั่งซื้อ
+		if err := tx.Where("weapon_id = ?", weapon.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&weapon).Error
+	})
+	if err != nil {
 		c.JSON(500, gin.H{"error": "ลบไม่สำเร็จ: " + err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"message": "ลบอาวุธเรียบร้อย"})
+	c.JSON(200, gin.H{"message": "เก็บอาวุธเข้าคลังเรียบร้อย"})
+}
+
+// GetArchivedWeapons - Admin lists archived weapons
+func GetArchivedWeapons(c *gin.Context) {
+	var weapons []models.Weapon
+	if err := config.DB.Unscoped().
+		Where("archived_at IS NOT NULL").
+		Order("archived_at desc").
+		Find(&weapons).Error; err != nil {
+		log.Printf("[ADMIN] list archived weapons failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "โหลดรายการอาวุธในคลังไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, weapons)
+}
+
+// RestoreWeapon - Admin brings an archived weapon back to the catalog
+func RestoreWeapon(c *gin.Context) {
+	id := c.Param("id")
+	var weapon models.Weapon
+
+	if err := config.DB.Unscoped().Where("archived_at IS NOT NULL").First(&weapon, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอาวุธที่ถูกเก็บเข้าคลัง"})
+		return
+	}
+
+	if err := config.DB.Unscoped().Model(&weapon).Update("archived_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "กู้คืนไม่สำเร็จ: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "กู้คืนอาวุธเรียบร้อย"})
 }
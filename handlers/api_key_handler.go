@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyDefaultDays = 90
+	apiKeyMaxDays     = 365
+	// apiKeyLimit caps the active keys per user.
+	apiKeyLimit = 20
+)
+
+var errAPIKeyLimit = errors.New("too many active api keys")
+
+// grantableScopes lists the scopes a user may put on a new key: the account
+// scopes plus every permission their role grants right now.
+func grantableScopes(user models.User) ([]string, error) {
+	role, err := models.LoadRole(config.DB, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := role.PermissionCodes(config.DB)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{models.ScopeAccountRead, models.ScopeAccountWrite}, perms...), nil
+}
+
+// GetAPIKeyScopes lists the scopes the caller can give a new key.
+func GetAPIKeyScopes(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+	scopes, err := grantableScopes(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงรายการ scope ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scopes": scopes})
+}
+
+// GetAPIKeys lists the caller's keys, newest first. The keys themselves are
+// never returned again after creation.
+func GetAPIKeys(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var keys []models.APIKey
+	if err := config.DB.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงรายการ API key ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey creates a named, scoped key that expires after expires_in_days
+// (default 90, at most 365). The plain key is only in this response.
+func CreateAPIKey(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var input struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุชื่อและ scope ของ API key"})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ชื่อ API key ต้องมี 1-100 ตัวอักษร"})
+		return
+	}
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = apiKeyDefaultDays
+	}
+	if input.ExpiresInDays < 1 || input.ExpiresInDays > apiKeyMaxDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days ต้องอยู่ระหว่าง 1 ถึง " + strconv.Itoa(apiKeyMaxDays)})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+	allowed, err := grantableScopes(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง API key ไม่สำเร็จ"})
+		return
+	}
+	scopes := models.ScopeList{}
+	for _, s := range input.Scopes {
+		if !models.ScopeList(allowed).Has(s) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "คุณให้ scope นี้กับ API key ไม่ได้", "scope": s})
+			return
+		}
+		if !scopes.Has(s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key ต้องมีอย่างน้อย 1 scope"})
+		return
+	}
+
+	plain, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง API key ไม่สำเร็จ"})
+		return
+	}
+	key := models.APIKey{
+		UserID:  user.ID,
+		Name:    input.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  scopes,
+		// A key made from a 2FA login counts as 2FA for roles that require it.
+		MFA:       c.GetBool("mfa"),
+		ExpiresAt: time.Now().AddDate(0, 0, input.ExpiresInDays),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active >= apiKeyLimit {
+			return errAPIKeyLimit
+		}
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, &user.ID, "api_key.created", "api_key", key.ID, map[string]interface{}{
+			"name":       key.Name,
+			"scopes":     key.Scopes,
+			"expires_at": key.ExpiresAt,
+		})
+	})
+	if errors.Is(err, errAPIKeyLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": "มี API key ที่ใช้งานอยู่ครบ " + strconv.Itoa(apiKeyLimit) + " อันแล้ว กรุณายกเลิกอันที่ไม่ใช้ก่อน"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] api key creation failed (uid=%d): %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง API key ไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[AUTH] api key #%d created (uid=%d, scopes=%v)", key.ID, user.ID, key.Scopes)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "สร้าง API key เรียบร้อย กรุณาเก็บ key นี้ไว้ จะไม่แสดงอีก",
+		"key":     plain,
+		"api_key": key,
+	})
+}
+
+// RevokeAPIKey revokes one of the caller's keys. It stops working at once.
+func RevokeAPIKey(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัส API key ไม่ถูกต้อง"})
+		return
+	}
+
+	var key models.APIKey
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error; err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := tx.Model(&key).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, &userID, "api_key.revoked", "api_key", key.ID, map[string]interface{}{
+			"name": key.Name,
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ API key"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิก API key ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ยกเลิก API key เรียบร้อย", "api_key": key})
+}
+
+// revokeUserAPIKeys revokes every active key of a user, e.g. after a
+// password reset.
+func revokeUserAPIKeys(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
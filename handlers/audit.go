@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/Bannawat01/ec-space/models"
+	"gorm.io/gorm"
+)
+
+// writeAudit appends an audit record inside tx. actorID is nil for actions
+// that come from the command line rather than a logged-in user.
+func writeAudit(tx *gorm.DB, actorID *uint, action, targetType string, targetID uint, details map[string]interface{}) error {
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    string(raw),
+	}).Error
+}
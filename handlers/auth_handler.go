@@ -1,40 +1,80 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/Bannawat01/ec-space/config"
 	"github.com/Bannawat01/ec-space/models"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
-var jwtKey = []byte("galactic_secret_key_99")
+// signupBonus is credited to every new account.
+var signupBonus = models.Credits(10000)
+
+// registerInput is the body of POST /api/register. Only these fields come
+// from the client; everything else on the account is set by the server.
+type registerInput struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint
+// violation, e.g. a username or email that is already taken.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
 
 // Register handler
 func Register(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var input registerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
 		return
 	}
 
-	user.Role = "user"
-	if user.Username == "admin_boss" {
-		user.Role = "admin"
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), 10)
+	if err != nil {
+		log.Printf("[AUTH] register: hash password failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลงทะเบียนไม่สำเร็จ"})
+		return
+	}
+	// สมัครผ่าน API ได้แค่ role user เสมอ — admin สร้างได้จาก CLI หรือ admin คนอื่นเท่านั้น
+	user := models.User{
+		Username: input.Username,
+		Email:    input.Email,
+		Password: string(hashedPassword),
+		Role:     models.RoleUser,
 	}
 
-	user.Credits = 10000
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), 10)
-	user.Password = string(hashedPassword)
-
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ชื่อผู้ใช้หรืออีเมลนี้ถูกใช้แล้ว หรือ " + err.Error()})
+	// สร้างบัญชีและลงโบนัสสมัครสมาชิกใน ledger ภายใน transaction เดียวกัน
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return adjustCreditsTx(tx, &user, signupBonus, models.CreditTransaction{
+			Type: models.CreditTxSignupBonus,
+		})
+	})
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "ชื่อผู้ใช้หรืออีเมลนี้ถูกใช้แล้ว"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] register failed (username=%q): %v", input.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลงทะเบียนไม่สำเร็จ"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "ลงทะเบียนสำเร็จ!"})
+	if err := sendVerificationEmail(user); err != nil {
+		log.Printf("[AUTH] verification email failed (uid=%d): %v", user.ID, err)
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "ลงทะเบียนสำเร็จ! กรุณายืนยันอีเมลจากลิงก์ที่ส่งไปให้"})
 }
 
 // Login handler
@@ -50,26 +90,52 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Where("username = ?", input.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "ไม่พบผู้ใช้"})
+	// ถูกหน่วง/ล็อกอยู่ → ไม่ตรวจรหัสผ่านเลย
+	ip := c.ClientIP()
+	if until, blocked := loginBlockedUntil(input.Username, ip); blocked {
+		respondLoginBlocked(c, until)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "รหัสผ่านไม่ถูกต้อง"})
+	// ไม่บอกว่าผิดที่ชื่อผู้ใช้หรือรหัสผ่าน และใช้เวลาเท่ากันทั้งสองกรณี
+	hash := dummyPasswordHash
+	found := config.DB.Where("username = ?", input.Username).First(&user).Error == nil
+	if found {
+		hash = []byte(user.Password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(input.Password)); err != nil || !found {
+		recordLoginFailure(input.Username, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": loginFailedMessage})
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	tokenString, _ := token.SignedString(jwtKey)
-	c.JSON(http.StatusOK, gin.H{"token": tokenString, "role": user.Role})
-}
+	// บัญชีที่เปิด 2FA ต้องยืนยันรหัสจากแอปที่ /api/login/2fa ก่อนถึงจะได้ token จริง
+	if user.TOTPEnabled {
+		challenge, exp, err := createLoginChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถเริ่มการยืนยัน 2FA ได้"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":    true,
+			"challenge_token": challenge,
+			"expires_at":      exp,
+		})
+		return
+	}
 
-func GetJWTKey() []byte {
-	return jwtKey
+	if err := clearLoginFailures(config.DB, user.Username); err != nil {
+		log.Printf("[AUTH] clear login failures failed (uid=%d): %v", user.ID, err)
+	}
+	pair, err := startTokenFamily(c, user, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถออก Token ได้"})
+		return
+	}
+	resp := pair.response(user)
+	// role ที่บังคับ 2FA จะใช้สิทธิ์หลังบ้านไม่ได้จนกว่าจะเปิด 2FA
+	if role, err := models.LoadRole(config.DB, user.Role); err == nil && role.Require2FA {
+		resp["mfa_enrollment_required"] = true
+	}
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRegisterInputBinding(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{`{"username":"alice","email":"alice@example.com","password":"correct horse"}`, false},
+		{`{"username":"alice","email":"alice@example.com","password":"12345678"}`, false},
+		{`{"username":"alice","email":"alice@example.com","password":"1234567"}`, true},
+		{`{"username":"alice","email":"alice@example.com","password":""}`, true},
+		{`{"username":"alice","email":"alice","password":"correct horse"}`, true},
+		{`{"username":"alice","email":"","password":"correct horse"}`, true},
+		{`{"email":"alice@example.com","password":"correct horse"}`, true},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/register", strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		var input registerInput
+		if err := c.ShouldBindJSON(&input); (err != nil) != tt.wantErr {
+			t.Errorf("bind %s: err = %v, wantErr %v", tt.body, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505", ConstraintName: "uni_users_email"}
+	if !isUniqueViolation(unique) || !isUniqueViolation(fmt.Errorf("create user: %w", unique)) {
+		t.Error("unique violation not recognised")
+	}
+	if isUniqueViolation(&pgconn.PgError{Code: "23502"}) || isUniqueViolation(fmt.Errorf("boom")) || isUniqueViolation(nil) {
+		t.Error("other errors taken for a unique violation")
+	}
+}
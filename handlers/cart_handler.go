@@ -19,8 +19,17 @@ func GetCart(c *gin.Context) {
 
 	userID := val.(uint)
 	var items []models.CartItem
-	config.DB.Preload("Weapon").Where("user_id = ?", userID).Find(&items)
-	c.JSON(200, items)
+	config.DB.Preload("Weapon").Preload("Variant").Where("user_id = ?", userID).Find(&items)
+
+	// Weapon หรือรุ่นที่ถูกเก็บเข้าคลังจะโหลดไม่ขึ้น ไม่ต้องแสดงในตะกร้า
+	visible := make([]models.CartItem, 0, len(items))
+	for _, it := range items {
+		if it.Weapon.ID != 0 && it.Variant.ID != 0 {
+			it.Variant.UnitPrice = it.Variant.PriceFor(it.Weapon)
+			visible = append(visible, it)
+		}
+	}
+	c.JSON(200, visible)
 }
 
 // AddToCart - Add item to cart
@@ -33,50 +42,74 @@ func AddToCart(c *gin.Context) {
 
 	userID := val.(uint)
 
+	// variant_id picks the variant; weapon_id alone is enough for weapons
+	// that have only one.
 	var input struct {
-		WeaponID uint `json:"weapon_id" binding:"required"`
-		Quantity int  `json:"quantity" binding:"required,min=1"`
+		WeaponID  uint `json:"weapon_id"`
+		VariantID uint `json:"variant_id"`
+		Quantity  int  `json:"quantity" binding:"required,min=1"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
 		return
 	}
+	if input.WeaponID == 0 && input.VariantID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: ต้องระบุ variant_id หรือ weapon_id"})
+		return
+	}
 
-	// Validate weapon exists and check stock
+	// Validate variant exists and check stock
+	var variant models.WeaponVariant
+	if input.VariantID != 0 {
+		if err := config.DB.First(&variant, input.VariantID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "สินค้าไม่พบ"})
+			return
+		}
+	} else {
+		var variants []models.WeaponVariant
+		config.DB.Where("weapon_id = ?", input.WeaponID).Limit(2).Find(&variants)
+		if len(variants) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาเลือกรุ่นของสินค้า"})
+			return
+		}
+		variant = variants[0]
+	}
 	var weapon models.Weapon
-	if err := config.DB.First(&weapon, input.WeaponID).Error; err != nil {
+	if err := config.DB.First(&weapon, variant.WeaponID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "สินค้าไม่พบ"})
 		return
 	}
 
 	// Check stock availability
-	if weapon.Stock < input.Quantity {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("สินค้ามีไม่เพียงพอ คงเหลือ %d ชิ้น", weapon.Stock)})
+	if variant.Stock < input.Quantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("สินค้ามีไม่เพียงพอ คงเหลือ %d ชิ้น", variant.Stock)})
 		return
 	}
 
 	var cartItem models.CartItem
-	err := config.DB.Where("user_id = ? AND weapon_id = ?", userID, input.WeaponID).First(&cartItem).Error
+	err := config.DB.Where("user_id = ? AND variant_id = ?", userID, variant.ID).First(&cartItem).Error
 
 	if err == nil {
 		// Item already in cart - update quantity
 		newQty := cartItem.Quantity + input.Quantity
-		if newQty > weapon.Stock {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("จำนวนที่ขอมากเกินไป คงเหลือ %d ชิ้น", weapon.Stock)})
+		if newQty > variant.Stock {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("จำนวนที่ขอมากเกินไป คงเหลือ %d ชิ้น", variant.Stock)})
 			return
 		}
 		config.DB.Model(&cartItem).Update("quantity", newQty)
 	} else {
 		// New item - create cart item
 		config.DB.Create(&models.CartItem{
-			UserID:   userID,
-			WeaponID: input.WeaponID,
-			Quantity: input.Quantity,
+			UserID:    userID,
+			WeaponID:  weapon.ID,
+			VariantID: variant.ID,
+			Quantity:  input.Quantity,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "บันทึกตะกร้าสำเร็จ", "weapon": weapon})
+	variant.UnitPrice = variant.PriceFor(weapon)
+	c.JSON(http.StatusOK, gin.H{"message": "บันทึกตะกร้าสำเร็จ", "weapon": weapon, "variant": variant})
 }
 
 // RemoveFromCart - Remove item from cart
@@ -88,7 +121,7 @@ func RemoveFromCart(c *gin.Context) {
 	}
 
 	userID := val.(uint)
-	weaponID := c.Param("weapon_id")
-	config.DB.Where("user_id = ? AND weapon_id = ?", userID, weaponID).Delete(&models.CartItem{})
+	variantID := c.Param("variant_id")
+	config.DB.Where("user_id = ? AND variant_id = ?", userID, variantID).Delete(&models.CartItem{})
 	c.JSON(200, gin.H{"message": "ลบสำเร็จ"})
 }
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	languagePattern     = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+	errCategoryNotFound = errors.New("category not found")
+	errCategoryInUse    = errors.New("category in use")
+)
+
+// categoryInput is the body of POST and PATCH /api/admin/categories. On
+// PATCH only the fields that are sent change; parent_id 0 moves the
+// category to the top level.
+type categoryInput struct {
+	Slug      *string           `json:"slug"`
+	Names     map[string]string `json:"names"`
+	ParentID  *uint             `json:"parent_id"`
+	SortOrder *int              `json:"sort_order"`
+	Icon      *string           `json:"icon"`
+}
+
+// apply copies the input onto cat and checks the result. The parent must
+// exist and must not be the category itself or one of its descendants.
+func (in categoryInput) apply(tx *gorm.DB, cat *models.Category) error {
+	if in.Slug != nil {
+		cat.Slug = strings.TrimSpace(*in.Slug)
+	}
+	if in.Names != nil {
+		names := models.LocalizedText{}
+		for lang, name := range in.Names {
+			lang, name = strings.ToLower(strings.TrimSpace(lang)), strings.TrimSpace(name)
+			if !languagePattern.MatchString(lang) {
+				return fmt.Errorf("รหัสภาษา %q ไม่ถูกต้อง", lang)
+			}
+			if utf8.RuneCountInString(name) > 100 {
+				return fmt.Errorf("ชื่อหมวดหมู่ (%s) ต้องไม่เกิน 100 ตัวอักษร", lang)
+			}
+			if name != "" {
+				names[lang] = name
+			}
+		}
+		cat.Names = names
+	}
+	if in.ParentID != nil {
+		cat.ParentID = in.ParentID
+		if *in.ParentID == 0 {
+			cat.ParentID = nil
+		}
+	}
+	if in.SortOrder != nil {
+		cat.SortOrder = *in.SortOrder
+	}
+	if in.Icon != nil {
+		cat.Icon = strings.TrimSpace(*in.Icon)
+	}
+
+	if len(cat.Slug) > 50 || !categorySlugPattern.MatchString(cat.Slug) {
+		return errors.New("slug ต้องเป็นตัวพิมพ์เล็ก a-z, 0-9 และ - ไม่เกิน 50 ตัวอักษร")
+	}
+	if cat.Names[models.DefaultLanguage] == "" {
+		return errors.New("ต้องมีชื่อหมวดหมู่ภาษาอังกฤษ (names.en)")
+	}
+	if len(cat.Icon) > 200 {
+		return errors.New("icon ต้องไม่เกิน 200 ตัวอักษร")
+	}
+
+	var taken int64
+	if err := tx.Model(&models.Category{}).Where("slug = ? AND id <> ?", cat.Slug, cat.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("slug %q ถูกใช้แล้ว", cat.Slug)
+	}
+
+	// Walk up from the new parent; meeting the category itself means a cycle.
+	for id := cat.ParentID; id != nil; {
+		if cat.ID != 0 && *id == cat.ID {
+			return errors.New("หมวดหมู่ย่อยของตัวเองเป็นหมวดหมู่แม่ไม่ได้")
+		}
+		var parent models.Category
+		if err := tx.Select("id", "parent_id").First(&parent, *id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("ไม่พบหมวดหมู่แม่")
+			}
+			return err
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+// categoryExists reports whether slug names a category, for validating
+// weapons.type before it hits the foreign key.
+func categoryExists(slug string) (bool, error) {
+	var n int64
+	err := config.DB.Model(&models.Category{}).Where("slug = ?", slug).Count(&n).Error
+	return n > 0, err
+}
+
+// GetCategories lists every category in display order (each parent followed
+// by its children) with its name in ?lang= (default en). count is the number
+// of weapons in the category itself and total_count includes subcategories.
+func GetCategories(c *gin.Context) {
+	lang := c.DefaultQuery("lang", models.DefaultLanguage)
+
+	var cats []models.Category
+	if err := config.DB.Order("sort_order, slug").Find(&cats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงหมวดหมู่ไม่สำเร็จ"})
+		return
+	}
+	var counts []facetCount
+	if err := config.DB.Model(&models.Weapon{}).
+		Select("type AS value, COUNT(*) AS count").
+		Group("type").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงหมวดหมู่ไม่สำเร็จ"})
+		return
+	}
+	direct := make(map[string]int64, len(counts))
+	for _, fc := range counts {
+		direct[fc.Value] = fc.Count
+	}
+
+	children := map[uint][]models.Category{}
+	var roots []models.Category
+	for _, cat := range cats {
+		if cat.ParentID == nil {
+			roots = append(roots, cat)
+		} else {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		}
+	}
+
+	type categoryResponse struct {
+		models.Category
+		Name       string `json:"name"`
+		Depth      int    `json:"depth"`
+		Count      int64  `json:"count"`
+		TotalCount int64  `json:"total_count"`
+	}
+	out := make([]categoryResponse, 0, len(cats))
+	var walk func(cat models.Category, depth int) int64
+	walk = func(cat models.Category, depth int) int64 {
+		i := len(out)
+		out = append(out, categoryResponse{
+			Category: cat,
+			Name:     cat.Names.Get(lang),
+			Depth:    depth,
+			Count:    direct[cat.Slug],
+		})
+		total := direct[cat.Slug]
+		for _, child := range children[cat.ID] {
+			total += walk(child, depth+1)
+		}
+		out[i].TotalCount = total
+		return total
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateCategory adds a category to the catalog.
+func CreateCategory(c *gin.Context) {
+	var input categoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var cat models.Category
+	var invalid error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if invalid = input.apply(tx, &cat); invalid != nil {
+			return invalid
+		}
+		return tx.Create(&cat).Error
+	})
+	if invalid != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] category create failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มหมวดหมู่ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "เพิ่มหมวดหมู่สำเร็จ!", "category": cat})
+}
+
+// UpdateCategory edits a category. Changing the slug moves its weapons with
+// it (ON UPDATE CASCADE).
+func UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสหมวดหมู่ไม่ถูกต้อง"})
+		return
+	}
+	var input categoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var cat models.Category
+	var invalid error
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&cat, id).Error; err != nil {
+			return err
+		}
+		if invalid = input.apply(tx, &cat); invalid != nil {
+			return invalid
+		}
+		return tx.Save(&cat).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหมวดหมู่"})
+		return
+	}
+	if invalid != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] category #%d update failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตหมวดหมู่ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "อัปเดตสำเร็จ!", "category": cat})
+}
+
+// DeleteCategory removes a category that has no subcategories and no
+// weapons, archived ones included since their orders still point at them.
+func DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสหมวดหมู่ไม่ถูกต้อง"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var cat models.Category
+		if err := tx.First(&cat, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errCategoryNotFound
+			}
+			return err
+		}
+		var weapons, subcategories int64
+		if err := tx.Unscoped().Model(&models.Weapon{}).Where("type = ?", cat.Slug).Count(&weapons).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", cat.ID).Count(&subcategories).Error; err != nil {
+			return err
+		}
+		if weapons > 0 || subcategories > 0 {
+			return errCategoryInUse
+		}
+		return tx.Delete(&cat).Error
+	})
+	switch {
+	case errors.Is(err, errCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหมวดหมู่"})
+	case errors.Is(err, errCategoryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "หมวดหมู่นี้ยังมีอาวุธหรือหมวดหมู่ย่อยอยู่ กรุณาย้ายออกก่อน"})
+	case err != nil:
+		log.Printf("[ADMIN] category #%d delete failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบหมวดหมู่ไม่สำเร็จ"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "ลบหมวดหมู่เรียบร้อย"})
+	}
+}
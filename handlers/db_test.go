@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Bannawat01/ec-space/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns an in-memory database with tables for the given models.
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// useTestDB points config.DB at a new test database for the rest of the test.
+func useTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	saved := config.DB
+	config.DB = newTestDB(t, tables...)
+	t.Cleanup(func() { config.DB = saved })
+	return config.DB
+}
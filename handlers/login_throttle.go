@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// loginFreeAttempts failures are allowed before any delay kicks in.
+	loginFreeAttempts = 3
+	// loginBaseDelay is the first backoff delay; it doubles per failure.
+	loginBaseDelay = time.Second
+)
+
+// loginFailedMessage is the only message a failed login gets, whether the
+// username exists or not.
+const loginFailedMessage = "ชื่อผู้ใช้หรือรหัสผ่านไม่ถูกต้อง"
+
+// dummyPasswordHash is compared against when the username doesn't exist, so
+// unknown and known usernames take the same time to reject.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("ec-space-timing-dummy"), 10)
+
+// loginFailureWindow is how long a key must stay quiet before its failure
+// count starts again from zero.
+func loginFailureWindow() time.Duration {
+	return 4 * config.App.Auth.LoginLockout.Duration
+}
+
+// throttleKey identifies one LoginThrottle row.
+type throttleKey struct {
+	scope, key string
+	limit      int
+}
+
+func loginThrottleKeys(username, ip string) []throttleKey {
+	a := config.App.Auth
+	return []throttleKey{
+		{models.ThrottleScopeUsername, strings.ToLower(strings.TrimSpace(username)), a.LoginMaxAttempts},
+		{models.ThrottleScopeIP, ip, a.LoginIPMaxAttempts},
+	}
+}
+
+// loginBlockedUntil returns when the username or IP may try again, if either
+// is currently backed off or locked.
+func loginBlockedUntil(username, ip string) (time.Time, bool) {
+	var until time.Time
+	for _, k := range loginThrottleKeys(username, ip) {
+		var t models.LoginThrottle
+		if err := config.DB.Where("scope = ? AND key = ?", k.scope, k.key).Limit(1).Find(&t).Error; err != nil {
+			log.Printf("[AUTH] throttle lookup failed: %v", err)
+			continue
+		}
+		if t.LockedUntil != nil && t.LockedUntil.After(until) {
+			until = *t.LockedUntil
+		}
+	}
+	return until, until.After(time.Now())
+}
+
+// loginDelay is the backoff after the given number of consecutive failures:
+// nothing for the first few, then doubling, then a full lockout once the
+// limit is reached.
+func loginDelay(failures, limit int) time.Duration {
+	lockout := config.App.Auth.LoginLockout.Duration
+	if failures >= limit {
+		return lockout
+	}
+	if failures <= loginFreeAttempts {
+		return 0
+	}
+	shift := failures - loginFreeAttempts - 1
+	if shift > 30 {
+		return lockout
+	}
+	d := loginBaseDelay << shift
+	if d > lockout {
+		d = lockout
+	}
+	return d
+}
+
+// recordLoginFailure counts a failed attempt against the username and the IP.
+func recordLoginFailure(username, ip string) {
+	now := time.Now()
+	for _, k := range loginThrottleKeys(username, ip) {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			row := models.LoginThrottle{Scope: k.scope, Key: k.key, LastFailureAt: now}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("scope = ? AND key = ?", k.scope, k.key).First(&row).Error; err != nil {
+				return err
+			}
+
+			if now.Sub(row.LastFailureAt) > loginFailureWindow() {
+				row.Failures = 0
+			}
+			row.Failures++
+			row.LastFailureAt = now
+			row.LockedUntil = nil
+			if d := loginDelay(row.Failures, k.limit); d > 0 {
+				until := now.Add(d)
+				row.LockedUntil = &until
+			}
+			if row.Failures == k.limit {
+				log.Printf("[AUTH] login locked for %s %q after %d failures", k.scope, k.key, row.Failures)
+			}
+			return tx.Model(&row).Select("failures", "last_failure_at", "locked_until").Updates(&row).Error
+		})
+		if err != nil {
+			log.Printf("[AUTH] throttle update failed (%s %q): %v", k.scope, k.key, err)
+		}
+	}
+
+	// Drop counters that have gone quiet so the table doesn't keep one row
+	// per IP forever.
+	config.DB.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-loginFailureWindow()), now).
+		Delete(&models.LoginThrottle{})
+}
+
+// clearLoginFailures forgets the failures for a username after it logs in
+// successfully or an admin unlocks it. The IP counter is left alone so one
+// good account can't be used to reset an attacker's budget.
+func clearLoginFailures(db *gorm.DB, username string) error {
+	return db.Where("scope = ? AND key = ?", models.ThrottleScopeUsername, strings.ToLower(strings.TrimSpace(username))).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// respondLoginBlocked answers a login attempt made while backed off or locked.
+func respondLoginBlocked(c *gin.Context, until time.Time) {
+	wait := int(time.Until(until).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(wait))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "พยายามเข้าสู่ระบบหลายครั้งเกินไป กรุณาลองใหม่ภายหลัง",
+		"retry_after": wait,
+	})
+}
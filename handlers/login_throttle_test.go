@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+)
+
+// withLoginLimits sets the throttle settings for one test.
+func withLoginLimits(t *testing.T, maxAttempts, ipMaxAttempts int, lockout time.Duration) {
+	t.Helper()
+	saved := config.App.Auth
+	config.App.Auth.LoginMaxAttempts = maxAttempts
+	config.App.Auth.LoginIPMaxAttempts = ipMaxAttempts
+	config.App.Auth.LoginLockout = config.Duration{Duration: lockout}
+	t.Cleanup(func() { config.App.Auth = saved })
+}
+
+func TestLoginDelay(t *testing.T) {
+	withLoginLimits(t, 10, 100, 15*time.Minute)
+
+	tests := []struct {
+		failures, limit int
+		want            time.Duration
+	}{
+		// Free attempts
+		{0, 10, 0},
+		{1, 10, 0},
+		{3, 10, 0},
+		// Doubling backoff
+		{4, 10, time.Second},
+		{5, 10, 2 * time.Second},
+		{6, 10, 4 * time.Second},
+		{7, 10, 8 * time.Second},
+		{8, 10, 16 * time.Second},
+		{9, 10, 32 * time.Second},
+		// Lockout from the limit on
+		{10, 10, 15 * time.Minute},
+		{11, 10, 15 * time.Minute},
+		{50, 10, 15 * time.Minute},
+		// The backoff never exceeds the lockout, and huge shifts don't overflow
+		{13, 100, 512 * time.Second},
+		{14, 100, 15 * time.Minute},
+		{40, 100, 15 * time.Minute},
+		{99, 100, 15 * time.Minute},
+		// A limit inside the free attempts still locks
+		{2, 2, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures, tt.limit); got != tt.want {
+			t.Errorf("loginDelay(%d, %d) = %s, want %s", tt.failures, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleKeys(t *testing.T) {
+	withLoginLimits(t, 10, 100, 15*time.Minute)
+
+	for _, name := range []string{"alice", "Alice", "  ALICE ", "aLiCe"} {
+		keys := loginThrottleKeys(name, "203.0.113.7")
+		if len(keys) != 2 {
+			t.Fatalf("got %d keys, want 2", len(keys))
+		}
+		if k := keys[0]; k.scope != models.ThrottleScopeUsername || k.key != "alice" || k.limit != 10 {
+			t.Errorf("username key for %q = %+v, want alice with limit 10", name, k)
+		}
+		if k := keys[1]; k.scope != models.ThrottleScopeIP || k.key != "203.0.113.7" || k.limit != 100 {
+			t.Errorf("IP key for %q = %+v, want the IP with limit 100", name, k)
+		}
+	}
+}
+
+func TestClearLoginFailuresIgnoresCase(t *testing.T) {
+	db := newTestDB(t, &models.LoginThrottle{})
+	now := time.Now()
+	db.Create(&[]models.LoginThrottle{
+		{Scope: models.ThrottleScopeUsername, Key: "alice", Failures: 4, LastFailureAt: now},
+		{Scope: models.ThrottleScopeIP, Key: "203.0.113.7", Failures: 4, LastFailureAt: now},
+	})
+
+	if err := clearLoginFailures(db, " Alice "); err != nil {
+		t.Fatal(err)
+	}
+	var rows []models.LoginThrottle
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].Scope != models.ThrottleScopeIP {
+		t.Errorf("rows left = %+v, want only the IP counter", rows)
+	}
+}
+
+func TestLoginLockoutThreshold(t *testing.T) {
+	withLoginLimits(t, 3, 100, time.Minute)
+	useTestDB(t, &models.LoginThrottle{})
+
+	// Every spelling counts against the same username, from any IP.
+	for i, name := range []string{"Alice", "alice", " ALICE"} {
+		if _, blocked := loginBlockedUntil("alice", "198.51.100.1"); blocked {
+			t.Fatalf("blocked after %d failures, limit is 3", i)
+		}
+		recordLoginFailure(name, "203.0.113.7")
+	}
+
+	until, blocked := loginBlockedUntil("aLiCe", "198.51.100.1")
+	if !blocked {
+		t.Fatal("not blocked after reaching the limit")
+	}
+	if left := time.Until(until); left < 50*time.Second || left > time.Minute {
+		t.Errorf("locked for another %s, want the 1m lockout", left)
+	}
+	if _, blocked := loginBlockedUntil("bob", "198.51.100.1"); blocked {
+		t.Error("another username on a clean IP is blocked")
+	}
+}
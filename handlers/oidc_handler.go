@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/Bannawat01/ec-space/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// oidcStateTTL is how long the user has to finish signing in at the provider.
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateCookie ties the callback to the browser that started the login,
+	// so a callback URL can't be replayed in someone else's browser.
+	oidcStateCookie = "oidc_state"
+)
+
+var (
+	errOIDCState           = errors.New("oidc state is invalid or expired")
+	errOIDCEmailUnverified = errors.New("provider did not return a verified email")
+	errOIDCLinkUnverified  = errors.New("existing account with this email is not verified")
+	usernameUnsafe         = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// GetOIDCProviders lists the identity providers shown as login buttons.
+func GetOIDCProviders(c *gin.Context) {
+	out := []gin.H{}
+	for _, p := range oidc.List() {
+		out = append(out, gin.H{
+			"name":         p.Name,
+			"display_name": p.DisplayName(),
+			"login_url":    "/api/oidc/" + p.Name + "/login",
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// OIDCLogin starts an authorization code + PKCE login and redirects the
+// browser to the provider.
+func OIDCLogin(c *gin.Context) {
+	p, err := oidc.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่รู้จักผู้ให้บริการเข้าสู่ระบบนี้"})
+		return
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เริ่มการเข้าสู่ระบบไม่สำเร็จ"})
+		return
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เริ่มการเข้าสู่ระบบไม่สำเร็จ"})
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เริ่มการเข้าสู่ระบบไม่สำเร็จ"})
+		return
+	}
+
+	authURL, err := p.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("[OIDC] %s discovery failed: %v", p.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "ติดต่อผู้ให้บริการเข้าสู่ระบบไม่ได้"})
+		return
+	}
+
+	now := time.Now()
+	config.DB.Where("expires_at < ?", now).Delete(&models.OIDCState{})
+	if err := config.DB.Create(&models.OIDCState{
+		StateHash:    auth.HashToken(state),
+		Provider:     p.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(oidcStateTTL),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เริ่มการเข้าสู่ระบบไม่สำเร็จ"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(oidcStateTTL.Seconds()), "/api/oidc", "", config.App.IsProduction(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback finishes the login when the provider redirects back. The
+// result goes to the frontend's /oidc/callback page in the URL fragment, so
+// tokens never reach server logs or Referer headers.
+func OIDCCallback(c *gin.Context) {
+	name := c.Param("provider")
+	p, err := oidc.Get(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่รู้จักผู้ให้บริการเข้าสู่ระบบนี้"})
+		return
+	}
+
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/oidc", "", config.App.IsProduction(), true)
+
+	if e := c.Query("error"); e != "" {
+		oidcRedirect(c, url.Values{"error": {"access_denied"}})
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		oidcRedirect(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	var st models.OIDCState
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND provider = ?", auth.HashToken(state), p.Name).
+			First(&st).Error; err != nil {
+			return errOIDCState
+		}
+		if err := tx.Delete(&st).Error; err != nil {
+			return err
+		}
+		if time.Now().After(st.ExpiresAt) {
+			return errOIDCState
+		}
+		return nil
+	})
+	if err != nil {
+		oidcRedirect(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rawIDToken, err := p.Exchange(ctx, code, st.CodeVerifier)
+	if err != nil {
+		log.Printf("[OIDC] %s code exchange failed: %v", p.Name, err)
+		oidcRedirect(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		log.Printf("[OIDC] %s id token rejected: %v", p.Name, err)
+		oidcRedirect(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	user, created, err := resolveOIDCUser(p.Name, claims)
+	switch {
+	case errors.Is(err, errOIDCEmailUnverified):
+		oidcRedirect(c, url.Values{"error": {"email_unverified"}})
+		return
+	case errors.Is(err, errOIDCLinkUnverified):
+		oidcRedirect(c, url.Values{"error": {"link_unverified"}})
+		return
+	case err != nil:
+		log.Printf("[OIDC] %s account resolution failed: %v", p.Name, err)
+		oidcRedirect(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	if created {
+		log.Printf("[OIDC] created user #%d (%s) from %s", user.ID, user.Username, p.Name)
+	}
+
+	// The provider replaces the password, not our own second factor.
+	if user.TOTPEnabled {
+		challenge, exp, err := createLoginChallenge(user)
+		if err != nil {
+			oidcRedirect(c, url.Values{"error": {"login_failed"}})
+			return
+		}
+		oidcRedirect(c, url.Values{
+			"mfa_required":    {"true"},
+			"challenge_token": {challenge},
+			"expires_at":      {exp.Format(time.RFC3339)},
+			"username":        {user.Username},
+		})
+		return
+	}
+
+	pair, err := startTokenFamily(c, user, false)
+	if err != nil {
+		oidcRedirect(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	oidcRedirect(c, url.Values{
+		"token":         {pair.AccessToken},
+		"refresh_token": {pair.RefreshToken},
+		"expires_at":    {pair.ExpiresAt.Format(time.RFC3339)},
+		"role":          {user.Role},
+		"username":      {user.Username},
+		"new_account":   {strconv.FormatBool(created)},
+	})
+}
+
+func oidcRedirect(c *gin.Context, fragment url.Values) {
+	target := strings.TrimRight(config.App.Server.FrontendURL, "/") + "/oidc/callback#" + fragment.Encode()
+	c.Redirect(http.StatusFound, target)
+}
+
+// resolveOIDCUser finds the user for a verified ID token. In order:
+//  1. an identity already linked to this provider + subject;
+//  2. a verified local account with the same email, which gets linked;
+//  3. a new account, when the provider vouches for the email.
+//
+// Unverified local accounts are never linked: whoever registered them may
+// not own the address, and linking would hand them this login.
+func resolveOIDCUser(provider string, claims *oidc.IDClaims) (models.User, bool, error) {
+	var (
+		user    models.User
+		created bool
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var ident models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&ident).Error
+		if err == nil {
+			return tx.First(&user, ident.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		email := strings.TrimSpace(claims.Email)
+		if email == "" || !bool(claims.EmailVerified) {
+			return errOIDCEmailUnverified
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		switch {
+		case err == nil:
+			if !user.Verified {
+				return errOIDCLinkUnverified
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if user, err = createOIDCUserTx(tx, email, claims); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    email,
+		}).Error
+	})
+	return user, created, err
+}
+
+// createOIDCUserTx creates a customer account for a first-time provider
+// login. It gets an unusable random password; the user can set a real one
+// through the forgot-password flow.
+func createOIDCUserTx(tx *gorm.DB, email string, claims *oidc.IDClaims) (models.User, error) {
+	random, err := oidc.RandomString(32)
+	if err != nil {
+		return models.User{}, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(random), 10)
+	if err != nil {
+		return models.User{}, err
+	}
+	username, err := uniqueUsernameTx(tx, claims.PreferredUsername, email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:   username,
+		Email:      email,
+		Password:   string(hashed),
+		Role:       models.RoleUser,
+		Verified:   true,
+		VerifiedAt: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, err
+	}
+	err = adjustCreditsTx(tx, &user, signupBonus, models.CreditTransaction{
+		Type: models.CreditTxSignupBonus,
+	})
+	return user, err
+}
+
+// uniqueUsernameTx derives a free username from the provider's preferred
+// username or the email's local part.
+func uniqueUsernameTx(tx *gorm.DB, preferred, email string) (string, error) {
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	if len(base) < 3 {
+		base = "commander"
+	}
+	if len(base) > 24 {
+		base = base[:24]
+	}
+
+	for i := 0; i < 20; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%d", base, i+1)
+		}
+		var n int64
+		if err := tx.Model(&models.User{}).Where("LOWER(username) = ?", candidate).Count(&n).Error; err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return candidate, nil
+		}
+	}
+	suffix, err := oidc.RandomString(4)
+	if err != nil {
+		return "", err
+	}
+	return base + "_" + strings.ToLower(usernameUnsafe.ReplaceAllString(suffix, "")), nil
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/middleware"
 	"github.com/Bannawat01/ec-space/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,15 +18,20 @@ import (
 
 // ── Request structs ───────────────────────────────────────────────────────────
 
-// CheckoutItem represents a single line in the cart payload.
+// CheckoutItem represents a single line in the cart payload. The variant
+// decides what is bought; weapon_id is optional and only checked against it.
 type CheckoutItem struct {
-	WeaponID uint `json:"weapon_id" binding:"required"`
-	Quantity int  `json:"quantity"  binding:"required,min=1"`
+	WeaponID  uint `json:"weapon_id"`
+	VariantID uint `json:"variant_id" binding:"required"`
+	Quantity  int  `json:"quantity"   binding:"required,min=1"`
 }
 
 // CheckoutRequest mirrors the JSON body sent by the React cart.
+//
+// Total is optional and only used as a consistency check: the amount charged
+// is always computed on the server from the locked variant and weapon rows.
 type CheckoutRequest struct {
-	Total float64        `json:"total" binding:"required,gt=0"`
+	Total *models.Money  `json:"total" binding:"omitempty,gt=0"`
 	Items []CheckoutItem `json:"items" binding:"required,min=1,dive"`
 }
 
@@ -36,33 +44,56 @@ func GetOrders(c *gin.Context) {
 
 	var orders []models.Order
 	config.DB.
-		Preload("Items.Weapon").
+		Preload("Items").
+		Preload("History", orderHistoryScope).
 		Where("user_id = ?", userID).
 		Order("created_at desc").
 		Find(&orders)
+	applyItemSnapshots(orders)
 
 	c.JSON(http.StatusOK, orders)
 }
 
+// orderHistoryScope sorts a preloaded status timeline oldest first.
+func orderHistoryScope(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at asc, id asc")
+}
+
+// applyItemSnapshots fills each item's weapon from its checkout snapshot so
+// order history is served exactly as it was purchased.
+func applyItemSnapshots(orders []models.Order) {
+	for i := range orders {
+		for j := range orders[i].Items {
+			item := &orders[i].Items[j]
+			item.Weapon = item.SnapshotWeapon()
+		}
+	}
+}
+
 // AdminOrderResponse is the shape returned by GetAllOrders.
 type AdminOrderResponse struct {
-	ID        uint               `json:"id"`
-	UserID    uint               `json:"user_id"`
-	Username  string             `json:"username"`
-	Address   string             `json:"address"`
-	Total     float64            `json:"total"`
-	Status    string             `json:"status"`
-	CreatedAt time.Time          `json:"created_at"`
-	Items     []models.OrderItem `json:"items"`
+	ID        uint                        `json:"id"`
+	UserID    uint                        `json:"user_id"`
+	Username  string                      `json:"username"`
+	Address   string                      `json:"address"`
+	Subtotal  models.Money                `json:"subtotal"`
+	Discount  models.Money                `json:"discount"`
+	Total     models.Money                `json:"total"`
+	Status    string                      `json:"status"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Items     []models.OrderItem          `json:"items"`
+	History   []models.OrderStatusHistory `json:"history"`
 }
 
 // GetAllOrders returns every order in the system (admin only).
 func GetAllOrders(c *gin.Context) {
 	var orders []models.Order
 	config.DB.
-		Preload("Items.Weapon").
+		Preload("Items").
+		Preload("History", orderHistoryScope).
 		Order("created_at desc").
 		Find(&orders)
+	applyItemSnapshots(orders)
 
 	// Collect unique user IDs
 	idSet := make(map[uint]struct{}, len(orders))
@@ -90,10 +121,13 @@ func GetAllOrders(c *gin.Context) {
 			UserID:    o.UserID,
 			Username:  u.Username,
 			Address:   u.Address,
+			Subtotal:  o.Subtotal,
+			Discount:  o.Discount,
 			Total:     o.Total,
 			Status:    o.Status,
 			CreatedAt: o.CreatedAt,
 			Items:     o.Items,
+			History:   o.History,
 		}
 	}
 
@@ -106,14 +140,16 @@ func GetAllOrders(c *gin.Context) {
 //  1. Bind and validate the request payload.
 //  2. BEGIN transaction.
 //  3. SELECT user FOR UPDATE  → lock row, prevent concurrent credit drain.
-//  4. Validate credits >= total.
-//  5. SELECT weapons FOR UPDATE → lock rows, prevent concurrent oversell.
-//  6. Validate every item has sufficient stock.
-//  7. UPDATE users SET credits = credits - total.
-//  8. INSERT orders record.
-//  9. INSERT order_items + UPDATE weapons SET stock = stock - qty  (per item).
-//  10. DELETE cart_items for this user.
-//  11. COMMIT.
+//  4. SELECT weapon_variants, then weapons FOR UPDATE → lock rows, prevent concurrent oversell.
+//  5. Validate every variant exists and has sufficient stock.
+//  6. Price the order from the locked rows and apply discounts.
+//  7. Reject if a client-supplied total does not match the computed total.
+//  8. Validate credits >= total.
+//  9. INSERT orders record + initial status history entry.
+//  10. UPDATE users SET credits = credits - total + INSERT credit ledger entry.
+//  11. INSERT order_items (price/name/variant snapshots) + UPDATE weapon_variants SET stock = stock - qty  (per item).
+//  12. DELETE cart_items for this user.
+//  13. COMMIT.
 func CreateOrder(c *gin.Context) {
 	// ── 0. Resolve authenticated user ────────────────────────────────────────
 	val, exists := c.Get("user_id")
@@ -129,6 +165,7 @@ func CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
 		return
 	}
+	items := mergeCheckoutItems(req.Items)
 
 	// ── 2. Begin transaction ─────────────────────────────────────────────────
 	tx := config.DB.Begin()
@@ -156,26 +193,35 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// ── 4. Credit check ───────────────────────────────────────────────────────
-	if user.Credits < req.Total {
+	// ── 4. Lock variant and weapon rows (SELECT … FOR UPDATE) ─────────────────
+	// Always in id order so two checkouts never wait on each other in a cycle.
+	variantIDs := make([]uint, len(items))
+	for i, it := range items {
+		variantIDs[i] = it.VariantID
+	}
+
+	var variants []models.WeaponVariant
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", variantIDs).
+		Order("id").
+		Find(&variants).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "เครดิตไม่พอ! กรุณาเติมเงินที่ธนาคารกลาง",
-			"have":  user.Credits,
-			"need":  req.Total,
-		})
+		log.Printf("[CHECKOUT] variant lock failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weapon data"})
 		return
 	}
 
-	// ── 5. Lock weapon rows (SELECT … FOR UPDATE) ─────────────────────────────
-	weaponIDs := make([]uint, len(req.Items))
-	for i, it := range req.Items {
-		weaponIDs[i] = it.WeaponID
+	variantMap := make(map[uint]models.WeaponVariant, len(variants))
+	weaponIDs := make([]uint, 0, len(variants))
+	for _, v := range variants {
+		variantMap[v.ID] = v
+		weaponIDs = append(weaponIDs, v.WeaponID)
 	}
 
 	var weapons []models.Weapon
 	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id IN ?", weaponIDs).
+		Order("id").
 		Find(&weapons).Error; err != nil {
 		tx.Rollback()
 		log.Printf("[CHECKOUT] weapon lock failed: %v", err)
@@ -189,41 +235,79 @@ func CreateOrder(c *gin.Context) {
 		weaponMap[w.ID] = w
 	}
 
-	// ── 6. Stock validation ───────────────────────────────────────────────────
-	for _, it := range req.Items {
-		w, ok := weaponMap[it.WeaponID]
-		if !ok {
+	// Category names for the order item snapshots.
+	slugs := make([]string, 0, len(weapons))
+	for _, w := range weapons {
+		slugs = append(slugs, w.Type)
+	}
+	var categories []models.Category
+	if err := tx.Where("slug IN ?", slugs).Find(&categories).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[CHECKOUT] category lookup failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weapon data"})
+		return
+	}
+	categoryNames := make(map[string]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.Slug] = cat.Names.Get(models.DefaultLanguage)
+	}
+
+	// ── 5. Stock validation ───────────────────────────────────────────────────
+	// Archived weapons and variants are excluded by the soft-delete scope, so
+	// they fail here.
+	for _, it := range items {
+		v, ok := variantMap[it.VariantID]
+		w, weaponOK := weaponMap[v.WeaponID]
+		if !ok || !weaponOK || (it.WeaponID != 0 && it.WeaponID != v.WeaponID) {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("weapon #%d not found", it.WeaponID),
+				"error": fmt.Sprintf("variant #%d not found or no longer available", it.VariantID),
 			})
 			return
 		}
-		if w.Stock < it.Quantity {
+		if v.Stock < it.Quantity {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error":     fmt.Sprintf("'%s' มีสินค้าไม่พอ", w.Name),
-				"available": w.Stock,
+				"error":     fmt.Sprintf("'%s' (%s) มีสินค้าไม่พอ", w.Name, v.SKU),
+				"available": v.Stock,
 				"requested": it.Quantity,
 			})
 			return
 		}
 	}
 
-	// ── 7. Deduct credits ─────────────────────────────────────────────────────
-	newCredits := user.Credits - req.Total
-	if err := tx.Model(&user).Update("credits", newCredits).Error; err != nil {
+	// ── 6. Server-side pricing ────────────────────────────────────────────────
+	breakdown, err := priceOrder(items, variantMap, weaponMap)
+	if err != nil {
 		tx.Rollback()
-		log.Printf("[CHECKOUT] credit deduction failed (uid=%d): %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct credits"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// ── 8. Create order header ────────────────────────────────────────────────
+	// ── 7. Client total consistency check ─────────────────────────────────────
+	if rejectTotalMismatch(c, req.Total, breakdown) {
+		tx.Rollback()
+		return
+	}
+
+	// ── 8. Credit check ───────────────────────────────────────────────────────
+	if user.Credits < breakdown.Total {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "เครดิตไม่พอ! กรุณาเติมเงินที่ธนาคารกลาง",
+			"have":  user.Credits,
+			"need":  breakdown.Total,
+		})
+		return
+	}
+
+	// ── 9. Create order header ────────────────────────────────────────────────
 	order := models.Order{
 		UserID:    userID,
-		Total:     req.Total,
-		Status:    "paid",
+		Subtotal:  breakdown.Subtotal,
+		Discount:  breakdown.DiscountTotal,
+		Total:     breakdown.Total,
+		Status:    models.OrderStatusPaid,
 		CreatedAt: time.Now(),
 	}
 	if err := tx.Create(&order).Error; err != nil {
@@ -232,32 +316,67 @@ func CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order record"})
 		return
 	}
+	if err := tx.Create(&models.OrderStatusHistory{
+		OrderID:   order.ID,
+		ToStatus:  models.OrderStatusPaid,
+		ChangedBy: userID,
+		CreatedAt: order.CreatedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[CHECKOUT] status history insert failed (oid=%d): %v", order.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record order status"})
+		return
+	}
 
-	// ── 9. Insert order items + deduct stock ──────────────────────────────────
-	for _, it := range req.Items {
+	// ── 10. Deduct credits (+ ledger entry) ───────────────────────────────────
+	if err := adjustCreditsTx(tx, &user, -breakdown.Total, models.CreditTransaction{
+		Type:    models.CreditTxPurchase,
+		OrderID: &order.ID,
+		ActorID: &userID,
+	}); err != nil {
+		tx.Rollback()
+		log.Printf("[CHECKOUT] credit deduction failed (uid=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct credits"})
+		return
+	}
+
+	// ── 11. Insert order items (with snapshots) + deduct stock ────────────────
+	for _, line := range breakdown.Lines {
+		w := weaponMap[line.WeaponID]
+		v := variantMap[line.VariantID]
 		item := models.OrderItem{
-			OrderID:  order.ID,
-			WeaponID: it.WeaponID,
-			Quantity: it.Quantity,
+			OrderID:            order.ID,
+			WeaponID:           line.WeaponID,
+			VariantID:          line.VariantID,
+			Quantity:           line.Quantity,
+			UnitPrice:          line.UnitPrice,
+			LineTotal:          line.LineTotal,
+			WeaponName:         w.Name,
+			WeaponType:         w.Type,
+			WeaponCategoryName: categoryNames[w.Type],
+			WeaponImage:        w.ImageURL,
+			SKU:                v.SKU,
+			VariantOptions:     v.Options,
 		}
 		if err := tx.Create(&item).Error; err != nil {
 			tx.Rollback()
-			log.Printf("[CHECKOUT] order item insert failed (wid=%d): %v", it.WeaponID, err)
+			log.Printf("[CHECKOUT] order item insert failed (vid=%d): %v", line.VariantID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record order items"})
 			return
 		}
 
-		if err := tx.Model(&models.Weapon{}).
-			Where("id = ?", it.WeaponID).
-			Update("stock", gorm.Expr("stock - ?", it.Quantity)).Error; err != nil {
+		// weapons.stock follows through the weapon_variants trigger.
+		if err := tx.Model(&models.WeaponVariant{}).
+			Where("id = ?", line.VariantID).
+			Update("stock", gorm.Expr("stock - ?", line.Quantity)).Error; err != nil {
 			tx.Rollback()
-			log.Printf("[CHECKOUT] stock deduction failed (wid=%d): %v", it.WeaponID, err)
+			log.Printf("[CHECKOUT] stock deduction failed (vid=%d): %v", line.VariantID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update weapon stock"})
 			return
 		}
 	}
 
-	// ── 10. Clear user cart ───────────────────────────────────────────────────
+	// ── 12. Clear user cart ───────────────────────────────────────────────────
 	if err := tx.Where("user_id = ?", userID).Delete(&models.CartItem{}).Error; err != nil {
 		tx.Rollback()
 		log.Printf("[CHECKOUT] cart clear failed (uid=%d): %v", userID, err)
@@ -265,20 +384,243 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// ── 11. Commit ────────────────────────────────────────────────────────────
+	// ── 13. Commit ────────────────────────────────────────────────────────────
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("[CHECKOUT] commit failed (uid=%d): %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
 		return
 	}
 
-	log.Printf("[CHECKOUT] OK — order #%d | user #%d | total %.2f CR | remaining %.2f CR",
-		order.ID, userID, req.Total, newCredits)
+	log.Printf("[CHECKOUT] OK — order #%d | user #%d | total %s CR | remaining %s CR",
+		order.ID, userID, breakdown.Total, user.Credits)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":           "สั่งซื้อสำเร็จ!",
 		"order_id":          order.ID,
-		"total":             req.Total,
-		"remaining_credits": newCredits,
+		"subtotal":          breakdown.Subtotal,
+		"discounts":         breakdown.Discounts,
+		"discount_total":    breakdown.DiscountTotal,
+		"total":             breakdown.Total,
+		"lines":             breakdown.Lines,
+		"remaining_credits": user.Credits,
+	})
+}
+
+var errInvalidTransition = errors.New("invalid order status transition")
+
+// UpdateOrderStatusRequest is the body accepted by UpdateOrderStatus.
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// UpdateOrderStatus moves an order along its lifecycle (admin only).
+// Only transitions allowed by models.CanTransitionOrder are accepted, and
+// every change is appended to the order's status history. Moving an order to
+// cancelled or refunded returns the credits to the customer.
+func UpdateOrderStatus(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	adminID := val.(uint)
+
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+		return
+	}
+	if !models.IsValidOrderStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status '%s'", req.Status)})
+		return
+	}
+
+	// Both of these return credits to the customer, so they need finance rights.
+	if (req.Status == models.OrderStatusCancelled || req.Status == models.OrderStatusRefunded) &&
+		!middleware.HasPermission(c, models.PermOrdersRefund) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      fmt.Sprintf("changing status to '%s' requires the %s permission", req.Status, models.PermOrdersRefund),
+			"permission": models.PermOrdersRefund,
+		})
+		return
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, c.Param("id")).Error; err != nil {
+			return err
+		}
+		return transitionOrderTx(tx, &order, req.Status, adminID, req.Note)
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("cannot change status from '%s' to '%s'", order.Status, req.Status),
+		})
+		return
+	case err != nil:
+		log.Printf("[ORDER] status update failed (oid=%s): %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		return
+	}
+
+	log.Printf("[ORDER] #%d status → %s by admin #%d", order.ID, req.Status, adminID)
+
+	config.DB.Preload("History", orderHistoryScope).First(&order, order.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "อัปเดตสถานะคำสั่งซื้อสำเร็จ",
+		"id":      order.ID,
+		"status":  order.Status,
+		"history": order.History,
+	})
+}
+
+// transitionOrderTx moves a locked order to status `to` inside tx and appends
+// the change to its history.
+//
+// Cancelling refunds the order total and puts the stock back; refunding
+// returns the credits only, since the goods may already be with the customer.
+func transitionOrderTx(tx *gorm.DB, order *models.Order, to string, actorID uint, note string) error {
+	if !models.CanTransitionOrder(order.Status, to) {
+		return errInvalidTransition
+	}
+
+	switch to {
+	case models.OrderStatusCancelled:
+		if err := refundOrderTx(tx, order, actorID, true); err != nil {
+			return err
+		}
+	case models.OrderStatusRefunded:
+		if err := refundOrderTx(tx, order, actorID, false); err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{"status": to}
+	if to == models.OrderStatusCancelled {
+		now := time.Now()
+		updates["cancel_reason"] = note
+		updates["cancelled_at"] = now
+	}
+	if err := tx.Model(order).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ChangedBy:  actorID,
+		Note:       note,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+	order.Status = to
+	return nil
+}
+
+// refundOrderTx returns an order's total to its owner and, when restock is
+// set, puts every line's quantity back on its variant. Rows are locked in the
+// same order CreateOrder uses (user first, then variants by id).
+func refundOrderTx(tx *gorm.DB, order *models.Order, actorID uint, restock bool) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&user, order.UserID).Error; err != nil {
+		return err
+	}
+	if err := adjustCreditsTx(tx, &user, order.Total, models.CreditTransaction{
+		Type:    models.CreditTxRefund,
+		OrderID: &order.ID,
+		ActorID: &actorID,
+	}); err != nil {
+		return err
+	}
+
+	if !restock {
+		return nil
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Order("variant_id").Find(&items).Error; err != nil {
+		return err
+	}
+	for _, it := range items {
+		// Unscoped: archived variants still get their stock back.
+		if err := tx.Unscoped().Model(&models.WeaponVariant{}).
+			Where("id = ?", it.VariantID).
+			Update("stock", gorm.Expr("stock + ?", it.Quantity)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelOrderRequest is the body accepted by CancelOrder.
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CancelOrder lets a customer cancel their own order before it ships.
+// The credits are refunded and the stock restored in one transaction.
+func CancelOrder(c *gin.Context) {
+	val, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userID := val.(uint)
+
+	var req CancelOrderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload: " + err.Error()})
+			return
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "cancelled by customer"
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			First(&order, c.Param("id")).Error; err != nil {
+			return err
+		}
+		return transitionOrderTx(tx, &order, models.OrderStatusCancelled, userID, reason)
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "คำสั่งซื้อนี้ไม่สามารถยกเลิกได้แล้ว",
+			"status": order.Status,
+		})
+		return
+	case err != nil:
+		log.Printf("[ORDER] cancel failed (oid=%s uid=%d): %v", c.Param("id"), userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
+		return
+	}
+
+	var user models.User
+	config.DB.First(&user, userID)
+
+	log.Printf("[ORDER] #%d cancelled by user #%d | refunded %s CR", order.ID, userID, order.Total)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "ยกเลิกคำสั่งซื้อสำเร็จ คืนเครดิตเรียบร้อย",
+		"order_id":          order.ID,
+		"status":            order.Status,
+		"refunded":          order.Total,
+		"remaining_credits": user.Credits,
 	})
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PricedLine is a single checkout line priced from the locked variant and
+// weapon rows.
+type PricedLine struct {
+	WeaponID  uint         `json:"weapon_id"`
+	VariantID uint         `json:"variant_id"`
+	SKU       string       `json:"sku"`
+	Name      string       `json:"name"`
+	UnitPrice models.Money `json:"unit_price"`
+	Quantity  int          `json:"quantity"`
+	LineTotal models.Money `json:"line_total"`
+}
+
+// AppliedDiscount describes one discount granted by a DiscountRule.
+type AppliedDiscount struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Amount      models.Money `json:"amount"`
+}
+
+// PriceBreakdown is the server-side quote returned to the client at checkout.
+type PriceBreakdown struct {
+	Lines         []PricedLine      `json:"lines"`
+	Subtotal      models.Money      `json:"subtotal"`
+	Discounts     []AppliedDiscount `json:"discounts"`
+	DiscountTotal models.Money      `json:"discount_total"`
+	Total         models.Money      `json:"total"`
+}
+
+// DiscountRule inspects a priced cart and returns the discount it grants, if any.
+// Rules only ever see server-side prices, never values sent by the client.
+type DiscountRule func(b *PriceBreakdown) (AppliedDiscount, bool)
+
+// discountRules are evaluated in order for every checkout.
+var discountRules []DiscountRule
+
+// mergeCheckoutItems collapses duplicate variant lines so stock and price
+// checks see the real requested quantity per variant.
+func mergeCheckoutItems(items []CheckoutItem) []CheckoutItem {
+	index := make(map[uint]int, len(items))
+	merged := make([]CheckoutItem, 0, len(items))
+	for _, it := range items {
+		if i, ok := index[it.VariantID]; ok {
+			merged[i].Quantity += it.Quantity
+			continue
+		}
+		index[it.VariantID] = len(merged)
+		merged = append(merged, it)
+	}
+	return merged
+}
+
+// priceOrder computes the breakdown for items using the given variant and
+// weapon rows. The caller is expected to have locked the rows and validated
+// that every requested variant exists.
+func priceOrder(items []CheckoutItem, variants map[uint]models.WeaponVariant, weapons map[uint]models.Weapon) (PriceBreakdown, error) {
+	b := PriceBreakdown{
+		Lines:     make([]PricedLine, 0, len(items)),
+		Discounts: []AppliedDiscount{},
+	}
+
+	for _, it := range items {
+		v, ok := variants[it.VariantID]
+		if !ok {
+			return PriceBreakdown{}, fmt.Errorf("variant #%d not found", it.VariantID)
+		}
+		w, ok := weapons[v.WeaponID]
+		if !ok {
+			return PriceBreakdown{}, fmt.Errorf("weapon #%d not found", v.WeaponID)
+		}
+		price := v.PriceFor(w)
+		line := PricedLine{
+			WeaponID:  w.ID,
+			VariantID: v.ID,
+			SKU:       v.SKU,
+			Name:      w.Name,
+			UnitPrice: price,
+			Quantity:  it.Quantity,
+			LineTotal: price.Mul(it.Quantity),
+		}
+		b.Lines = append(b.Lines, line)
+		b.Subtotal += line.LineTotal
+	}
+
+	for _, rule := range discountRules {
+		d, ok := rule(&b)
+		if !ok || d.Amount <= 0 {
+			continue
+		}
+		b.Discounts = append(b.Discounts, d)
+		b.DiscountTotal += d.Amount
+	}
+	if b.DiscountTotal > b.Subtotal {
+		b.DiscountTotal = b.Subtotal
+	}
+	b.Total = b.Subtotal - b.DiscountTotal
+
+	return b, nil
+}
+
+// rejectTotalMismatch answers 409 with the server's breakdown when the client
+// sent a total that no longer matches it, e.g. because a price changed while
+// the item sat in the cart. It reports whether it responded.
+func rejectTotalMismatch(c *gin.Context, submitted *models.Money, b PriceBreakdown) bool {
+	if submitted == nil || *submitted == b.Total {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     "ราคาสินค้ามีการเปลี่ยนแปลง กรุณาตรวจสอบตะกร้าอีกครั้ง",
+		"submitted": *submitted,
+		"breakdown": b,
+	})
+	return true
+}
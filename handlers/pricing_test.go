@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMergeCheckoutItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []CheckoutItem
+		want  []CheckoutItem
+	}{
+		{
+			name:  "single line",
+			items: []CheckoutItem{{VariantID: 1, Quantity: 2}},
+			want:  []CheckoutItem{{VariantID: 1, Quantity: 2}},
+		},
+		{
+			name:  "duplicates are summed",
+			items: []CheckoutItem{{VariantID: 1, Quantity: 2}, {VariantID: 1, Quantity: 3}},
+			want:  []CheckoutItem{{VariantID: 1, Quantity: 5}},
+		},
+		{
+			name: "first occurrence keeps its position",
+			items: []CheckoutItem{
+				{VariantID: 2, Quantity: 1},
+				{VariantID: 1, Quantity: 1},
+				{VariantID: 2, Quantity: 4},
+			},
+			want: []CheckoutItem{{VariantID: 2, Quantity: 5}, {VariantID: 1, Quantity: 1}},
+		},
+		{
+			name:  "variants of one weapon stay apart",
+			items: []CheckoutItem{{WeaponID: 7, VariantID: 1, Quantity: 1}, {WeaponID: 7, VariantID: 2, Quantity: 1}},
+			want:  []CheckoutItem{{WeaponID: 7, VariantID: 1, Quantity: 1}, {WeaponID: 7, VariantID: 2, Quantity: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeCheckoutItems(tt.items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCheckoutItems() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckoutRequestQuantity(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{`{"items":[{"variant_id":1,"quantity":1}]}`, false},
+		{`{"items":[{"variant_id":1,"quantity":0}]}`, true},
+		{`{"items":[{"variant_id":1,"quantity":-2}]}`, true},
+		{`{"items":[{"variant_id":1}]}`, true},
+		{`{"items":[{"variant_id":1,"quantity":1},{"variant_id":2,"quantity":-1}]}`, true},
+		{`{"items":[]}`, true},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		var req CheckoutRequest
+		if err := c.ShouldBindJSON(&req); (err != nil) != tt.wantErr {
+			t.Errorf("bind %s: err = %v, wantErr %v", tt.body, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPriceOrder(t *testing.T) {
+	override := models.Money(80000)
+	weapons := map[uint]models.Weapon{
+		1: {ID: 1, Name: "Plasma Rifle", Price: 150000},
+		2: {ID: 2, Name: "Ion Blade", Price: 99950},
+	}
+	variants := map[uint]models.WeaponVariant{
+		10: {ID: 10, WeaponID: 1, SKU: "W1"},
+		11: {ID: 11, WeaponID: 1, SKU: "W1-MK2", Price: &override},
+		20: {ID: 20, WeaponID: 2, SKU: "W2"},
+	}
+
+	b, err := priceOrder(mergeCheckoutItems([]CheckoutItem{
+		{VariantID: 10, Quantity: 1},
+		{VariantID: 11, Quantity: 2},
+		{VariantID: 20, Quantity: 1},
+		{VariantID: 10, Quantity: 1},
+	}), variants, weapons)
+	if err != nil {
+		t.Fatalf("priceOrder: %v", err)
+	}
+	wantLines := []models.Money{300000, 160000, 99950}
+	if len(b.Lines) != len(wantLines) {
+		t.Fatalf("got %d lines, want %d", len(b.Lines), len(wantLines))
+	}
+	for i, want := range wantLines {
+		if b.Lines[i].LineTotal != want {
+			t.Errorf("line %d (%s) total = %s, want %s", i, b.Lines[i].SKU, b.Lines[i].LineTotal, want)
+		}
+	}
+	if b.Subtotal != 559950 || b.Total != 559950 {
+		t.Errorf("subtotal/total = %s/%s, want 5599.50", b.Subtotal, b.Total)
+	}
+
+	if _, err := priceOrder([]CheckoutItem{{VariantID: 99, Quantity: 1}}, variants, weapons); err == nil {
+		t.Error("priceOrder with an unknown variant: want error")
+	}
+}
+
+func TestRejectTotalMismatch(t *testing.T) {
+	b := PriceBreakdown{Subtotal: 150000, Total: 150000}
+	same, stale := models.Money(150000), models.Money(120000)
+	tests := []struct {
+		name      string
+		submitted *models.Money
+		want      int
+	}{
+		{"no total sent", nil, 0},
+		{"total matches", &same, 0},
+		{"stale total", &stale, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			responded := rejectTotalMismatch(c, tt.submitted, b)
+			if responded != (tt.want != 0) {
+				t.Fatalf("responded = %v, want %v", responded, tt.want != 0)
+			}
+			if !responded {
+				return
+			}
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			var body struct {
+				Submitted models.Money   `json:"submitted"`
+				Breakdown PriceBreakdown `json:"breakdown"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Submitted != *tt.submitted || body.Breakdown.Total != b.Total {
+				t.Errorf("body = %+v, want submitted %s and total %s", body, *tt.submitted, b.Total)
+			}
+		})
+	}
+}
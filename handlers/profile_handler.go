@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -28,14 +29,22 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
+	// ให้ frontend รู้ว่าควรแสดงเมนูหลังบ้านส่วนไหน
+	permissions := []string{}
+	if role, err := models.LoadRole(config.DB, user.Role); err == nil {
+		permissions, _ = role.PermissionCodes(config.DB)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"credits":  user.Credits,
-		"role":     user.Role,
-		"email":    user.Email,
-		"address":  user.Address,
-		"avatar":   user.Avatar,
+		"id":          user.ID,
+		"username":    user.Username,
+		"credits":     user.Credits,
+		"role":        user.Role,
+		"permissions": permissions,
+		"verified":    user.Verified,
+		"email":       user.Email,
+		"address":     user.Address,
+		"avatar":      user.Avatar,
 	})
 }
 
@@ -67,10 +76,22 @@ func UpdateProfile(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+	var current models.User
+	if err := db.First(&current, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+
 	updates := map[string]interface{}{}
+	emailChanged := input.Email != "" && !strings.EqualFold(input.Email, current.Email)
 	if input.Email != "" {
 		updates["email"] = input.Email
 	}
+	// อีเมลใหม่ต้องยืนยันใหม่
+	if emailChanged {
+		updates["verified"] = false
+		updates["verified_at"] = nil
+	}
 	if input.Address != "" {
 		updates["address"] = input.Address
 	}
@@ -80,8 +101,9 @@ func UpdateProfile(c *gin.Context) {
 	if err == nil && file != nil {
 		// ensure extension preserved
 		ext := filepath.Ext(file.Filename)
-		filename := fmt.Sprintf("uploads/avatar_%d_%d%s", userID, time.Now().Unix(), ext)
-		if err := c.SaveUploadedFile(file, filename); err != nil {
+		name := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().Unix(), ext)
+		filename := "uploads/" + name
+		if err := c.SaveUploadedFile(file, filepath.Join(config.App.Server.UploadDir, name)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอัพโหลดไฟล์ได้"})
 			return
 		}
@@ -101,43 +123,14 @@ func UpdateProfile(c *gin.Context) {
 	var user models.User
 	db.First(&user, userID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "อัพเดตโปรไฟล์เรียบร้อย",
-		"profile": gin.H{"id": user.ID, "username": user.Username, "email": user.Email, "address": user.Address, "avatar": user.Avatar, "credits": user.Credits},
-	})
-}
-
-// Topup - Add credits to user account
-func Topup(c *gin.Context) {
-	val, exists := c.Get("user_id")
-	if !exists || val == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "เซสชันหมดอายุ กรุณาล็อกอินใหม่"})
-		return
-	}
-
-	userID := val.(uint)
-
-	var input struct {
-		Amount float64 `json:"amount"`
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลการเติมเงินไม่ถูกต้อง"})
-		return
-	}
-
-	db := config.GetDB()
-	if err := db.Model(&models.User{}).Where("id = ?", userID).
-		Update("credits", db.Raw("credits + ?", input.Amount)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ระบบธนาคารกลางขัดข้อง"})
-		return
+	if emailChanged {
+		if err := sendVerificationEmail(user); err != nil {
+			log.Printf("[AUTH] verification email failed (uid=%d): %v", user.ID, err)
+		}
 	}
 
-	var user models.User
-	config.DB.First(&user, userID)
-
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "เติมเครดิตสำเร็จ!",
-		"new_balance": user.Credits,
+		"message": "อัพเดตโปรไฟล์เรียบร้อย",
+		"profile": gin.H{"id": user.ID, "username": user.Username, "email": user.Email, "address": user.Address, "avatar": user.Avatar, "credits": user.Credits, "verified": user.Verified},
 	})
 }
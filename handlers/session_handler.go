@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// activeSessions selects a user's sessions that can still be used: not
+// revoked and with a refresh token that hasn't been used or expired.
+func activeSessions(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.TokenFamily{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.family_id = token_families.id AND rt.used_at IS NULL AND rt.expires_at > ?)", time.Now())
+}
+
+func listSessions(userID uint, currentID string) ([]gin.H, error) {
+	var families []models.TokenFamily
+	if err := activeSessions(config.DB, userID).Order("last_seen_at DESC NULLS LAST").Find(&families).Error; err != nil {
+		return nil, err
+	}
+	out := make([]gin.H, 0, len(families))
+	for _, f := range families {
+		out = append(out, gin.H{
+			"id":           f.ID,
+			"device":       f.Device,
+			"ip":           f.IP,
+			"user_agent":   f.UserAgent,
+			"mfa":          f.MFA,
+			"created_at":   f.CreatedAt,
+			"last_seen_at": f.LastSeenAt,
+			"current":      f.ID == currentID,
+		})
+	}
+	return out, nil
+}
+
+// GetSessions lists where the caller is logged in. The session making the
+// request is marked current.
+func GetSessions(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	sessions, err := listSessions(userID, c.GetString("family_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงรายการ session ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs out one of the caller's sessions, on any device.
+// Ending the current session is the same as logging out.
+func RevokeSession(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	res := config.DB.Model(&models.TokenFamily{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": "ended by user"})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิก session ไม่สำเร็จ"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "ออกจากระบบอุปกรณ์นั้นเรียบร้อย",
+		"current": c.Param("id") == c.GetString("family_id"),
+	})
+}
+
+// RevokeOtherSessions signs out every session of the caller except the one
+// making the request.
+func RevokeOtherSessions(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	res := config.DB.Model(&models.TokenFamily{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, c.GetString("family_id")).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": "ended by user"})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิก session ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "ออกจากระบบอุปกรณ์อื่นทั้งหมดเรียบร้อย",
+		"revoked": res.RowsAffected,
+	})
+}
+
+// GetUserSessions lets staff see where a user is logged in.
+func GetUserSessions(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผู้ใช้ไม่ถูกต้อง"})
+		return
+	}
+	sessions, err := listSessions(uint(targetID), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงรายการ session ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// ForceLogoutUser ends every session of a user. With api_keys: true their
+// API keys are revoked as well.
+func ForceLogoutUser(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	adminID := val.(uint)
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผู้ใช้ไม่ถูกต้อง"})
+		return
+	}
+	var input struct {
+		APIKeys bool   `json:"api_keys"`
+		Reason  string `json:"reason"`
+	}
+	// The body is optional.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+			return
+		}
+	}
+
+	var (
+		user    models.User
+		revoked int64
+	)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, targetID).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.TokenFamily{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": "ended by staff"})
+		if res.Error != nil {
+			return res.Error
+		}
+		revoked = res.RowsAffected
+		if input.APIKeys {
+			if err := revokeUserAPIKeys(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		return writeAudit(tx, &adminID, "user.sessions.revoked", "user", user.ID, map[string]interface{}{
+			"sessions": revoked,
+			"api_keys": input.APIKeys,
+			"reason":   input.Reason,
+		})
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้ใช้"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] force logout failed (uid=%d): %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บังคับออกจากระบบไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[ADMIN] user #%d logged out everywhere by staff #%d (%d sessions)", user.ID, adminID, revoked)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "บังคับออกจากระบบทุกอุปกรณ์เรียบร้อย",
+		"id":       user.ID,
+		"username": user.Username,
+		"sessions": revoked,
+	})
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// signingKeyPublishLead is how long a new key sits in the JWKS before it
+	// signs anything, so services that cache the JWKS see it first.
+	signingKeyPublishLead = time.Hour
+	// signingKeyCheckInterval is how often every instance reloads the keys
+	// from the database and checks whether a rotation is due. It must be well
+	// below signingKeyPublishLead.
+	signingKeyCheckInterval = 5 * time.Minute
+	// signingKeyLeeway keeps an old key around a little past the lifetime of
+	// the last token it signed.
+	signingKeyLeeway = time.Minute
+)
+
+var jwtKeys = auth.NewKeyring()
+
+// JWTKeys returns the keyring that signs and verifies access tokens.
+func JWTKeys() *auth.Keyring {
+	return jwtKeys
+}
+
+func asymmetricJWT() bool {
+	return config.App.Auth.JWTAlgorithm != auth.AlgHS256
+}
+
+// LoadSigningKeys fills the keyring for the configured algorithm. With HS256
+// that is the shared secret. With RS256 or EdDSA the keys live in the
+// signing_keys table, and one is created if none of that algorithm is active
+// (first start, or the algorithm was just changed).
+func LoadSigningKeys() error {
+	a := config.App.Auth
+	if !asymmetricJWT() {
+		jwtKeys.Replace([]auth.Key{auth.NewHMACKey([]byte(a.JWTSecret))})
+		return nil
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockSigningKeys(tx); err != nil {
+			return err
+		}
+		now := time.Now()
+		var active int64
+		if err := tx.Model(&models.SigningKey{}).
+			Where("algorithm = ? AND activates_at <= ? AND (expires_at IS NULL OR expires_at > ?)", a.JWTAlgorithm, now, now).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return nil
+		}
+		_, err := createSigningKeyTx(tx, now, now.Add(a.AccessTokenTTL.Duration+signingKeyLeeway))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := reloadSigningKeys(); err != nil {
+		return err
+	}
+	// Stored keys that can't be opened (jwt_secret changed) are useless;
+	// start over with a fresh key.
+	if _, err := jwtKeys.SigningKey(time.Now()); err != nil {
+		log.Printf("[AUTH] no usable signing key, rotating immediately")
+		_, _, err = RotateSigningKeys(true, true)
+		return err
+	}
+	return nil
+}
+
+// lockSigningKeys serialises key creation between server instances.
+func lockSigningKeys(tx *gorm.DB) error {
+	return tx.Exec("LOCK TABLE signing_keys IN SHARE ROW EXCLUSIVE MODE").Error
+}
+
+// createSigningKeyTx stores a new key of the configured algorithm that
+// starts signing at activatesAt, and schedules every older key to expire at
+// retireOldAt.
+func createSigningKeyTx(tx *gorm.DB, activatesAt, retireOldAt time.Time) (models.SigningKey, error) {
+	a := config.App.Auth
+	key, err := auth.GenerateKey(a.JWTAlgorithm, activatesAt)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	sealed, err := key.SealPrivateKey([]byte(a.JWTSecret))
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	row := models.SigningKey{
+		ID:          key.ID,
+		Algorithm:   key.Alg,
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+	}
+	if err := tx.Create(&row).Error; err != nil {
+		return row, err
+	}
+	if err := tx.Model(&models.SigningKey{}).
+		Where("id <> ? AND (expires_at IS NULL OR expires_at > ?)", row.ID, retireOldAt).
+		Update("expires_at", retireOldAt).Error; err != nil {
+		return row, err
+	}
+	log.Printf("[AUTH] created %s signing key %s (signs from %s)", row.Algorithm, row.ID, activatesAt.Format(time.RFC3339))
+	return row, nil
+}
+
+// reloadSigningKeys loads every key that is still accepted into the keyring.
+// Keys that can't be decrypted are skipped.
+func reloadSigningKeys() error {
+	var rows []models.SigningKey
+	now := time.Now()
+	if err := config.DB.Where("expires_at IS NULL OR expires_at > ?", now).Find(&rows).Error; err != nil {
+		return err
+	}
+	keys := make([]auth.Key, 0, len(rows))
+	for _, r := range rows {
+		k, err := auth.OpenKey(r.ID, r.Algorithm, r.PrivateKey, []byte(config.App.Auth.JWTSecret), r.ActivatesAt, r.ExpiresAt)
+		if err != nil {
+			log.Printf("[AUTH] skipping signing key %s: %v", r.ID, err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	jwtKeys.Replace(keys)
+	return nil
+}
+
+// RotateSigningKeys creates the next signing key when the newest one is older
+// than auth.jwt_key_rotation, or always when force is set. The new key is
+// published now and signs after signingKeyPublishLead; older keys stay valid
+// until the tokens they signed have expired.
+//
+// immediate is for a leaked key: the new key signs at once and every older
+// key stops being accepted, so clients have to use their refresh tokens.
+func RotateSigningKeys(force, immediate bool) (models.SigningKey, bool, error) {
+	a := config.App.Auth
+	var (
+		created models.SigningKey
+		rotated bool
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockSigningKeys(tx); err != nil {
+			return err
+		}
+		now := time.Now()
+		var newest models.SigningKey
+		if err := tx.Where("algorithm = ?", a.JWTAlgorithm).Order("activates_at DESC").Limit(1).Find(&newest).Error; err != nil {
+			return err
+		}
+		due := force || newest.ID == "" ||
+			(a.JWTKeyRotation.Duration > 0 && !now.Before(newest.ActivatesAt.Add(a.JWTKeyRotation.Duration-signingKeyPublishLead)))
+		if !due {
+			return nil
+		}
+
+		activatesAt := now.Add(signingKeyPublishLead)
+		retireOldAt := activatesAt.Add(a.AccessTokenTTL.Duration + signingKeyLeeway)
+		if immediate || newest.ID == "" {
+			activatesAt = now
+			retireOldAt = now.Add(a.AccessTokenTTL.Duration + signingKeyLeeway)
+		}
+		if immediate {
+			retireOldAt = now
+		}
+		var err error
+		if created, err = createSigningKeyTx(tx, activatesAt, retireOldAt); err != nil {
+			return err
+		}
+		rotated = true
+		// Expired keys are only kept a day for troubleshooting.
+		return tx.Where("expires_at < ?", now.Add(-24*time.Hour)).Delete(&models.SigningKey{}).Error
+	})
+	if err != nil {
+		return created, false, err
+	}
+	return created, rotated, reloadSigningKeys()
+}
+
+// ListSigningKeys returns every stored signing key, newest first.
+func ListSigningKeys() ([]models.SigningKey, error) {
+	var rows []models.SigningKey
+	err := config.DB.Order("activates_at DESC").Find(&rows).Error
+	return rows, err
+}
+
+// RunSigningKeyRotation keeps the keyring in step with the database and
+// rotates keys on schedule. It runs for the life of the server; with HS256
+// there is nothing to do.
+func RunSigningKeyRotation() {
+	if !asymmetricJWT() {
+		return
+	}
+	for range time.Tick(signingKeyCheckInterval) {
+		if _, _, err := RotateSigningKeys(false, false); err != nil {
+			log.Printf("[AUTH] signing key rotation failed: %v", err)
+		}
+	}
+}
+
+// GetJWKS publishes the public signing keys so other services can verify
+// access tokens. It is empty when tokens are signed with HS256.
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/jwk-set+json", JWTKeys().JWKS(time.Now()))
+}
@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const specTextMaxLength = 200
+
+var (
+	specKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,29}$`)
+	specTypes      = map[string]bool{
+		models.SpecNumber: true, models.SpecInteger: true, models.SpecText: true,
+		models.SpecEnum: true, models.SpecBoolean: true,
+	}
+)
+
+// categorySchema returns the spec attributes that apply to weapons in the
+// category slug: its own and those of every parent, ordered for display. If
+// a key is defined more than once the nearest category wins.
+func categorySchema(db *gorm.DB, slug string) ([]models.SpecAttribute, error) {
+	var attrs []models.SpecAttribute
+	if err := db.Raw(`
+		WITH RECURSIVE lineage AS (
+			SELECT id, parent_id, 0 AS depth FROM categories WHERE slug = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, lineage.depth + 1 FROM categories c JOIN lineage ON c.id = lineage.parent_id
+		)
+		SELECT spec_attributes.* FROM spec_attributes
+		JOIN lineage ON lineage.id = spec_attributes.category_id
+		ORDER BY lineage.depth, spec_attributes.id`, slug).
+		Scan(&attrs).Error; err != nil {
+		return nil, err
+	}
+	schema := make([]models.SpecAttribute, 0, len(attrs))
+	seen := map[string]bool{}
+	for _, a := range attrs {
+		if !seen[a.Key] {
+			seen[a.Key] = true
+			schema = append(schema, a)
+		}
+	}
+	sort.SliceStable(schema, func(i, j int) bool { return schema[i].SortOrder < schema[j].SortOrder })
+	return schema, nil
+}
+
+// decodeSpecs parses the specs form field of the weapon admin endpoints, a
+// JSON object of attribute key to value. A null value removes the spec.
+func decodeSpecs(raw string) (map[string]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var specs map[string]interface{}
+	if err := dec.Decode(&specs); err != nil || specs == nil {
+		return nil, errors.New("specs ต้องเป็น JSON object")
+	}
+	return specs, nil
+}
+
+// applySpecs merges input into current and checks the result against the
+// category schema. Specs the schema doesn't know are dropped from current
+// (the weapon moved category) but rejected in input.
+func applySpecs(schema []models.SpecAttribute, current models.Specs, input map[string]interface{}) (models.Specs, error) {
+	byKey := make(map[string]models.SpecAttribute, len(schema))
+	for _, a := range schema {
+		byKey[a.Key] = a
+	}
+	out := models.Specs{}
+	for k, v := range current {
+		if _, ok := byKey[k]; ok {
+			out[k] = v
+		}
+	}
+	for k, v := range input {
+		attr, ok := byKey[k]
+		if !ok {
+			return nil, fmt.Errorf("หมวดหมู่นี้ไม่มี spec %q", k)
+		}
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		value, err := checkSpecValue(attr, v)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			delete(out, k)
+		} else {
+			out[k] = value
+		}
+	}
+	for _, a := range schema {
+		if _, ok := out[a.Key]; a.Required && !ok {
+			return nil, fmt.Errorf("ต้องระบุ %s (%s)", a.Names.Get(models.DefaultLanguage), a.Key)
+		}
+	}
+	return out, nil
+}
+
+// checkSpecValue converts v to the attribute's type and checks its bounds
+// and options. Empty text counts as no value and returns nil.
+func checkSpecValue(a models.SpecAttribute, v interface{}) (interface{}, error) {
+	name := a.Names.Get(models.DefaultLanguage)
+	switch a.Type {
+	case models.SpecNumber, models.SpecInteger:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%s ต้องเป็นตัวเลข", name)
+		}
+		f, err := n.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%s ต้องเป็นตัวเลข", name)
+		}
+		if (a.Min != nil && f < *a.Min) || (a.Max != nil && f > *a.Max) {
+			return nil, fmt.Errorf("%s ต้องอยู่ในช่วง %s", name, specRange(a))
+		}
+		if a.Type == models.SpecInteger {
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("%s ต้องเป็นจำนวนเต็ม", name)
+			}
+			return i, nil
+		}
+		return f, nil
+	case models.SpecBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s ต้องเป็น true หรือ false", name)
+		}
+		return b, nil
+	case models.SpecEnum:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s ต้องเป็นข้อความ", name)
+		}
+		for _, o := range a.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s ต้องเป็นหนึ่งใน %s", name, strings.Join(a.Options, ", "))
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s ต้องเป็นข้อความ", name)
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		if utf8.RuneCountInString(s) > specTextMaxLength {
+			return nil, fmt.Errorf("%s ต้องไม่เกิน %d ตัวอักษร", name, specTextMaxLength)
+		}
+		return s, nil
+	}
+}
+
+func specRange(a models.SpecAttribute) string {
+	bound := func(f *float64) string {
+		if f == nil {
+			return "∞"
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	return bound(a.Min) + " ถึง " + bound(a.Max)
+}
+
+// specLine is one row of a weapon's spec sheet.
+type specLine struct {
+	Key   string      `json:"key"`
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Unit  string      `json:"unit"`
+	Value interface{} `json:"value"`
+}
+
+// specSheet lists the weapon's specs in schema order with names in lang.
+func specSheet(schema []models.SpecAttribute, specs models.Specs, lang string) []specLine {
+	sheet := []specLine{}
+	for _, a := range schema {
+		if v, ok := specs[a.Key]; ok {
+			sheet = append(sheet, specLine{Key: a.Key, Name: a.Names.Get(lang), Type: a.Type, Unit: a.Unit, Value: v})
+		}
+	}
+	return sheet
+}
+
+// specAttributeInput is the body of POST /api/admin/categories/:id/specs and
+// PATCH /api/admin/specs/:id. key and type can't change once created; on
+// PATCH "min": null or "max": null removes the bound.
+type specAttributeInput struct {
+	Key        *string           `json:"key"`
+	Names      map[string]string `json:"names"`
+	Type       *string           `json:"type"`
+	Unit       *string           `json:"unit"`
+	Options    []string          `json:"options"`
+	Min        json.RawMessage   `json:"min"`
+	Max        json.RawMessage   `json:"max"`
+	Required   *bool             `json:"required"`
+	Filterable *bool             `json:"filterable"`
+	SortOrder  *int              `json:"sort_order"`
+}
+
+// apply copies the input onto attr and checks the result. A key may be
+// used only once along a category's line of parents and subcategories.
+func (in specAttributeInput) apply(tx *gorm.DB, attr *models.SpecAttribute) error {
+	if in.Key != nil {
+		key := strings.TrimSpace(*in.Key)
+		if attr.ID != 0 && key != attr.Key {
+			return errors.New("เปลี่ยน key ของ spec ไม่ได้ ให้สร้างใหม่แทน")
+		}
+		attr.Key = key
+	}
+	if in.Type != nil {
+		if attr.ID != 0 && *in.Type != attr.Type {
+			return errors.New("เปลี่ยนชนิดของ spec ไม่ได้ ให้สร้างใหม่แทน")
+		}
+		attr.Type = *in.Type
+	}
+	if in.Names != nil {
+		names := models.LocalizedText{}
+		for lang, name := range in.Names {
+			lang, name = strings.ToLower(strings.TrimSpace(lang)), strings.TrimSpace(name)
+			if !languagePattern.MatchString(lang) {
+				return fmt.Errorf("รหัสภาษา %q ไม่ถูกต้อง", lang)
+			}
+			if utf8.RuneCountInString(name) > 100 {
+				return fmt.Errorf("ชื่อ spec (%s) ต้องไม่เกิน 100 ตัวอักษร", lang)
+			}
+			if name != "" {
+				names[lang] = name
+			}
+		}
+		attr.Names = names
+	}
+	if in.Unit != nil {
+		attr.Unit = strings.TrimSpace(*in.Unit)
+	}
+	if in.Options != nil {
+		options := models.StringList{}
+		seen := map[string]bool{}
+		for _, o := range in.Options {
+			if o = strings.TrimSpace(o); o == "" || len(o) > 50 {
+				return errors.New("ตัวเลือกแต่ละค่าต้องมี 1-50 ตัวอักษร")
+			}
+			if !seen[o] {
+				seen[o] = true
+				options = append(options, o)
+			}
+		}
+		attr.Options = options
+	}
+	for _, b := range []struct {
+		raw json.RawMessage
+		dst **float64
+	}{{in.Min, &attr.Min}, {in.Max, &attr.Max}} {
+		if len(b.raw) == 0 {
+			continue
+		}
+		if bytes.Equal(bytes.TrimSpace(b.raw), []byte("null")) {
+			*b.dst = nil
+			continue
+		}
+		var f float64
+		if err := json.Unmarshal(b.raw, &f); err != nil {
+			return errors.New("min และ max ต้องเป็นตัวเลข")
+		}
+		*b.dst = &f
+	}
+	if in.Required != nil {
+		attr.Required = *in.Required
+	}
+	if in.Filterable != nil {
+		attr.Filterable = *in.Filterable
+	}
+	if in.SortOrder != nil {
+		attr.SortOrder = *in.SortOrder
+	}
+	if attr.Options == nil {
+		attr.Options = models.StringList{}
+	}
+
+	if !specKeyPattern.MatchString(attr.Key) {
+		return errors.New("key ต้องขึ้นต้นด้วย a-z และมีแค่ a-z, 0-9 หรือ _ ไม่เกิน 30 ตัวอักษร")
+	}
+	if !specTypes[attr.Type] {
+		return errors.New("type ต้องเป็น number, integer, text, enum หรือ boolean")
+	}
+	if attr.Names[models.DefaultLanguage] == "" {
+		return errors.New("ต้องมีชื่อ spec ภาษาอังกฤษ (names.en)")
+	}
+	if len(attr.Unit) > 20 {
+		return errors.New("unit ต้องไม่เกิน 20 ตัวอักษร")
+	}
+	if attr.Type == models.SpecEnum && len(attr.Options) == 0 {
+		return errors.New("spec แบบ enum ต้องมี options")
+	}
+	if attr.Type != models.SpecEnum && len(attr.Options) > 0 {
+		return errors.New("options ใช้ได้กับ spec แบบ enum เท่านั้น")
+	}
+	numeric := attr.Type == models.SpecNumber || attr.Type == models.SpecInteger
+	if !numeric && (attr.Min != nil || attr.Max != nil) {
+		return errors.New("min และ max ใช้ได้กับ spec แบบ number และ integer เท่านั้น")
+	}
+	if attr.Min != nil && attr.Max != nil && *attr.Min > *attr.Max {
+		return errors.New("min ต้องไม่มากกว่า max")
+	}
+
+	var taken int64
+	if err := tx.Raw(`
+		WITH RECURSIVE up AS (
+			SELECT id, parent_id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id, c.parent_id FROM categories c JOIN up ON c.id = up.parent_id
+		), down AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN down ON c.parent_id = down.id
+		)
+		SELECT COUNT(*) FROM spec_attributes
+		WHERE key = ? AND id <> ?
+		  AND (category_id IN (SELECT id FROM up) OR category_id IN (SELECT id FROM down))`,
+		attr.CategoryID, attr.CategoryID, attr.Key, attr.ID).
+		Scan(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("key %q ถูกใช้แล้วในหมวดหมู่แม่หรือหมวดหมู่ย่อย", attr.Key)
+	}
+	return nil
+}
+
+// GetCategorySpecs lists the spec attributes that apply to a category's
+// weapons, inherited ones included, with name in ?lang= (default en).
+func GetCategorySpecs(c *gin.Context) {
+	lang := c.DefaultQuery("lang", models.DefaultLanguage)
+
+	var cat models.Category
+	if err := config.DB.First(&cat, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหมวดหมู่"})
+		return
+	}
+	schema, err := categorySchema(config.DB, cat.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึง spec ของหมวดหมู่ไม่สำเร็จ"})
+		return
+	}
+
+	type specAttributeResponse struct {
+		models.SpecAttribute
+		Name      string `json:"name"`
+		Inherited bool   `json:"inherited"`
+	}
+	out := make([]specAttributeResponse, 0, len(schema))
+	for _, a := range schema {
+		out = append(out, specAttributeResponse{
+			SpecAttribute: a,
+			Name:          a.Names.Get(lang),
+			Inherited:     a.CategoryID != cat.ID,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateSpecAttribute adds a spec attribute to a category's schema.
+func CreateSpecAttribute(c *gin.Context) {
+	var input specAttributeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var cat models.Category
+	if err := config.DB.First(&cat, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหมวดหมู่"})
+		return
+	}
+
+	attr := models.SpecAttribute{CategoryID: cat.ID}
+	var invalid error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if invalid = input.apply(tx, &attr); invalid != nil {
+			return invalid
+		}
+		return tx.Create(&attr).Error
+	})
+	if invalid != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] spec attribute create failed (category #%d): %v", cat.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่ม spec ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "เพิ่ม spec สำเร็จ!", "spec": attr})
+}
+
+// UpdateSpecAttribute edits a spec attribute. Tightened rules (required,
+// bounds, options) apply to weapons the next time they are saved.
+func UpdateSpecAttribute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัส spec ไม่ถูกต้อง"})
+		return
+	}
+	var input specAttributeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var attr models.SpecAttribute
+	var invalid error
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&attr, id).Error; err != nil {
+			return err
+		}
+		if invalid = input.apply(tx, &attr); invalid != nil {
+			return invalid
+		}
+		return tx.Save(&attr).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ spec"})
+		return
+	}
+	if invalid != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] spec attribute #%d update failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดต spec ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "อัปเดตสำเร็จ!", "spec": attr})
+}
+
+// DeleteSpecAttribute removes a spec attribute and its values from every
+// weapon in the category and its subcategories.
+func DeleteSpecAttribute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัส spec ไม่ถูกต้อง"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var attr models.SpecAttribute
+		if err := tx.First(&attr, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			WITH RECURSIVE tree AS (
+				SELECT id, slug FROM categories WHERE id = ?
+				UNION
+				SELECT c.id, c.slug FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			UPDATE weapons SET specs = specs - ?::text WHERE type IN (SELECT slug FROM tree)`,
+			attr.CategoryID, attr.Key).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&attr).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ spec"})
+	case err != nil:
+		log.Printf("[ADMIN] spec attribute #%d delete failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบ spec ไม่สำเร็จ"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "ลบ spec เรียบร้อย"})
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/Bannawat01/ec-space/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errRefreshRejected = errors.New("refresh token rejected")
+
+// tokenPair is what Login and RefreshToken hand back to the client.
+type tokenPair struct {
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+func (p tokenPair) response(user models.User) gin.H {
+	return gin.H{
+		"token":         p.AccessToken,
+		"expires_at":    p.ExpiresAt,
+		"refresh_token": p.RefreshToken,
+		"role":          user.Role,
+	}
+}
+
+// startTokenFamily opens a new token family (session) for a fresh login and
+// issues its first access + refresh token. mfa records whether the login
+// passed 2FA; the device, IP and user agent come from the request.
+func startTokenFamily(c *gin.Context, user models.User, mfa bool) (tokenPair, error) {
+	var pair tokenPair
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		pair, err = startTokenFamilyTx(c, tx, user, mfa)
+		return err
+	})
+	return pair, err
+}
+
+func startTokenFamilyTx(c *gin.Context, tx *gorm.DB, user models.User, mfa bool) (tokenPair, error) {
+	now := time.Now()
+	ua := c.Request.UserAgent()
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	family := models.TokenFamily{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		MFA:        mfa,
+		Device:     utils.DeviceName(ua),
+		IP:         c.ClientIP(),
+		UserAgent:  ua,
+		LastSeenAt: &now,
+	}
+	if err := tx.Create(&family).Error; err != nil {
+		return tokenPair{}, err
+	}
+	return issueTokenPairTx(tx, user, family)
+}
+
+// issueTokenPairTx stores a new refresh token in the family and signs a
+// matching access token.
+func issueTokenPairTx(tx *gorm.DB, user models.User, family models.TokenFamily) (tokenPair, error) {
+	plain, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+	if err := tx.Create(&models.RefreshToken{
+		FamilyID:  family.ID,
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.App.Auth.RefreshTokenTTL.Duration),
+	}).Error; err != nil {
+		return tokenPair{}, err
+	}
+
+	access, exp, err := auth.IssueAccessToken(JWTKeys(), user.ID, user.Role, family.ID, family.MFA,
+		config.App.Auth.AccessTokenTTL.Duration)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{AccessToken: access, ExpiresAt: exp, RefreshToken: plain}, nil
+}
+
+// revokeFamily ends a token family. Already-revoked families keep their
+// original reason.
+func revokeFamily(tx *gorm.DB, familyID, reason string) error {
+	return tx.Model(&models.TokenFamily{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// revokeUserFamilies ends every active login of a user.
+func revokeUserFamilies(tx *gorm.DB, userID uint, reason string) error {
+	return tx.Model(&models.TokenFamily{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RefreshToken exchanges a refresh token for a new access + refresh token.
+//
+// Refresh tokens are single-use. Presenting one that was already used means
+// it was copied, so the whole family is revoked and every device on that
+// login has to sign in again.
+func RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาส่ง refresh_token"})
+		return
+	}
+
+	var (
+		pair     tokenPair
+		user     models.User
+		reused   bool
+		familyID string
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var rt models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", auth.HashToken(input.RefreshToken)).
+			First(&rt).Error; err != nil {
+			return errRefreshRejected
+		}
+		familyID = rt.FamilyID
+
+		var family models.TokenFamily
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&family, "id = ?", rt.FamilyID).Error; err != nil {
+			return errRefreshRejected
+		}
+		if family.RevokedAt != nil {
+			return errRefreshRejected
+		}
+
+		if rt.UsedAt != nil {
+			reused = true
+			return nil
+		}
+		if time.Now().After(rt.ExpiresAt) {
+			return errRefreshRejected
+		}
+
+		if err := tx.Model(&rt).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&user, rt.UserID).Error; err != nil {
+			return errRefreshRejected
+		}
+		if err := tx.Model(&family).Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip":           c.ClientIP(),
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		pair, err = issueTokenPairTx(tx, user, family)
+		return err
+	})
+
+	if reused {
+		// Revoke outside the rotation transaction so it always sticks.
+		if err := revokeFamily(config.DB, familyID, "refresh token reuse detected"); err != nil {
+			log.Printf("[AUTH] family revoke failed (fid=%s): %v", familyID, err)
+		}
+		log.Printf("[AUTH] refresh token reuse detected — family %s revoked", familyID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token ถูกใช้ซ้ำ กรุณาเข้าสู่ระบบใหม่"})
+		return
+	}
+	if errors.Is(err, errRefreshRejected) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token ไม่ถูกต้องหรือหมดอายุ"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] refresh failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถออก Token ได้"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pair.response(user))
+}
+
+// Logout revokes the token family of the current access token, which also
+// invalidates its refresh tokens.
+func Logout(c *gin.Context) {
+	familyID := c.GetString("family_id")
+	if familyID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "กรุณาเข้าสู่ระบบก่อน"})
+		return
+	}
+	if err := revokeFamily(config.DB, familyID, "logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ออกจากระบบไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ออกจากระบบเรียบร้อย"})
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/Bannawat01/ec-space/payments"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errTopupDailyLimit aborts CreateTopupIntent's transaction when the amount
+// would exceed the daily limit.
+var errTopupDailyLimit = errors.New("daily top-up limit exceeded")
+
+// topupLimits returns the configured top-up limits as Money.
+func topupLimits() (min, max, daily models.Money) {
+	p := config.App.Payments
+	return models.Credits(p.TopupMin), models.Credits(p.TopupMax), models.Credits(p.TopupDaily)
+}
+
+// CreateTopupIntent starts a top-up. No credits are added here; the intent
+// stays pending until the payment provider confirms it. Sending the same
+// Idempotency-Key header twice returns the original intent.
+func CreateTopupIntent(c *gin.Context) {
+	val, exists := c.Get("user_id")
+	if !exists || val == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "เซสชันหมดอายุ กรุณาล็อกอินใหม่"})
+		return
+	}
+	userID := val.(uint)
+
+	var input struct {
+		Amount models.Money `json:"amount" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลการเติมเงินไม่ถูกต้อง"})
+		return
+	}
+
+	var idemKey *string
+	if k := strings.TrimSpace(c.GetHeader("Idempotency-Key")); k != "" {
+		idemKey = &k
+		var existing models.TopupIntent
+		if err := config.DB.Where("user_id = ? AND idempotency_key = ?", userID, k).
+			First(&existing).Error; err == nil {
+			c.JSON(http.StatusOK, existing)
+			return
+		}
+	}
+
+	minAmount, maxAmount, dailyLimit := topupLimits()
+	if input.Amount < minAmount || input.Amount > maxAmount {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "จำนวนเงินไม่อยู่ในช่วงที่อนุญาต",
+			"min":   minAmount,
+			"max":   maxAmount,
+		})
+		return
+	}
+
+	provider, err := payments.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ระบบชำระเงินยังไม่พร้อมใช้งาน"})
+		return
+	}
+
+	// The user row is locked so concurrent requests can't both fit under the
+	// daily limit: the sum and the new intent are one atomic step.
+	var used models.Money
+	var createErr error
+	intent := models.TopupIntent{
+		UserID:         userID,
+		Amount:         input.Amount,
+		Status:         models.TopupPending,
+		Provider:       provider.Name(),
+		IdempotencyKey: idemKey,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TopupIntent{}).
+			Where("user_id = ? AND status IN ? AND created_at > ?",
+				userID, []string{models.TopupPending, models.TopupSucceeded}, time.Now().Add(-24*time.Hour)).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&used).Error; err != nil {
+			return err
+		}
+		if used+input.Amount > dailyLimit {
+			return errTopupDailyLimit
+		}
+		createErr = tx.Create(&intent).Error
+		return createErr
+	})
+	switch {
+	case errors.Is(err, errTopupDailyLimit):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "เกินวงเงินเติมเครดิตต่อวัน",
+			"daily":     dailyLimit,
+			"remaining": max(dailyLimit-used, 0),
+		})
+		return
+	case createErr != nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "ไม่สามารถสร้างรายการเติมเงินได้"})
+		return
+	case err != nil:
+		log.Printf("[TOPUP] create intent failed (user=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ระบบธนาคารกลางขัดข้อง"})
+		return
+	}
+
+	pi, err := provider.CreateIntent(c.Request.Context(), payments.IntentRequest{
+		IntentID: intent.ID,
+		UserID:   userID,
+		Amount:   int64(intent.Amount),
+	})
+	if err != nil {
+		log.Printf("[TOPUP] provider %s create failed (intent=%d): %v", provider.Name(), intent.ID, err)
+		config.DB.Model(&intent).Update("status", models.TopupFailed)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "ระบบธนาคารกลางขัดข้อง"})
+		return
+	}
+
+	intent.ProviderRef = &pi.Reference
+	intent.ClientSecret = pi.ClientSecret
+	intent.RedirectURL = pi.RedirectURL
+	config.DB.Model(&intent).Updates(map[string]interface{}{
+		"provider_ref":  pi.Reference,
+		"client_secret": pi.ClientSecret,
+		"redirect_url":  pi.RedirectURL,
+	})
+
+	c.JSON(http.StatusCreated, intent)
+}
+
+// GetTopupIntent returns one of the user's top-up intents.
+func GetTopupIntent(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var intent models.TopupIntent
+	if err := config.DB.Where("user_id = ?", userID).First(&intent, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการเติมเงิน"})
+		return
+	}
+	c.JSON(http.StatusOK, intent)
+}
+
+// ConfirmTopupIntent asks the provider whether the payment went through and
+// credits the account if it did. Safe to call any number of times.
+func ConfirmTopupIntent(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var intent models.TopupIntent
+	if err := config.DB.Where("user_id = ?", userID).First(&intent, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการเติมเงิน"})
+		return
+	}
+
+	if intent.Status == models.TopupPending && intent.ProviderRef != nil {
+		provider, err := payments.Get(intent.Provider)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ระบบชำระเงินยังไม่พร้อมใช้งาน"})
+			return
+		}
+		status, err := provider.FetchStatus(c.Request.Context(), *intent.ProviderRef)
+		if err != nil {
+			log.Printf("[TOPUP] provider %s status failed (intent=%d): %v", provider.Name(), intent.ID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "ไม่สามารถตรวจสอบสถานะการชำระเงินได้"})
+			return
+		}
+		settled, err := settleTopupIntent(intent.ID, status)
+		if err != nil {
+			log.Printf("[TOPUP] settle failed (intent=%d): %v", intent.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ระบบธนาคารกลางขัดข้อง"})
+			return
+		}
+		intent = settled
+	}
+
+	var user models.User
+	config.DB.First(&user, userID)
+
+	switch intent.Status {
+	case models.TopupSucceeded:
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "เติมเครดิตสำเร็จ!",
+			"intent":      intent,
+			"new_balance": user.Credits,
+		})
+	case models.TopupFailed:
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "การชำระเงินไม่สำเร็จ", "intent": intent})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "กำลังรอการยืนยันการชำระเงิน", "intent": intent})
+	}
+}
+
+// PaymentWebhook receives signed callbacks from a payment provider.
+func PaymentWebhook(c *gin.Context) {
+	provider, err := payments.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+
+	ev, err := provider.ParseWebhook(c.Request)
+	if err != nil {
+		log.Printf("[TOPUP] webhook rejected (%s): %v", provider.Name(), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook"})
+		return
+	}
+
+	var intent models.TopupIntent
+	if err := config.DB.Where("provider = ? AND provider_ref = ?", provider.Name(), ev.Reference).
+		First(&intent).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown payment reference"})
+		return
+	}
+	// An event without an amount is rejected too: it could confirm any intent.
+	if ev.Amount != int64(intent.Amount) {
+		log.Printf("[TOPUP] webhook amount mismatch (intent=%d): got %d want %d", intent.ID, ev.Amount, intent.Amount)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount mismatch"})
+		return
+	}
+
+	if _, err := settleTopupIntent(intent.ID, ev.Status); err != nil {
+		log.Printf("[TOPUP] settle failed (intent=%d): %v", intent.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "settlement failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// settleTopupIntent applies a provider status to a pending intent. The intent
+// row is locked, so concurrent webhooks and confirms credit the user at most
+// once; the unique ledger index on topup_id backs this up in the database.
+func settleTopupIntent(intentID uint, status payments.Status) (models.TopupIntent, error) {
+	var intent models.TopupIntent
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&intent, intentID).Error; err != nil {
+			return err
+		}
+		if intent.Status != models.TopupPending {
+			return nil // already settled
+		}
+
+		switch status {
+		case payments.StatusSucceeded:
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, intent.UserID).Error; err != nil {
+				return err
+			}
+			if err := adjustCreditsTx(tx, &user, intent.Amount, models.CreditTransaction{
+				Type:    models.CreditTxTopup,
+				TopupID: &intent.ID,
+				ActorID: &intent.UserID,
+			}); err != nil {
+				return err
+			}
+			now := time.Now()
+			intent.Status = models.TopupSucceeded
+			intent.ConfirmedAt = &now
+			return tx.Model(&intent).Updates(map[string]interface{}{
+				"status":       intent.Status,
+				"confirmed_at": now,
+			}).Error
+		case payments.StatusFailed:
+			intent.Status = models.TopupFailed
+			return tx.Model(&intent).Update("status", intent.Status).Error
+		}
+		return nil
+	})
+	return intent, err
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/Bannawat01/ec-space/payments"
+	"github.com/gin-gonic/gin"
+)
+
+// newTopupTest sets up a database with one user and a fake provider as the
+// default one, and returns both.
+func newTopupTest(t *testing.T) (models.User, *payments.FakeProvider) {
+	t.Helper()
+	db := useTestDB(t, &models.User{}, &models.TopupIntent{}, &models.CreditTransaction{})
+	user := models.User{Username: "pilot", Email: "pilot@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	fake := payments.NewFakeProvider("whsec", false)
+	payments.Register(fake)
+	if err := payments.SetDefault(fake.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := config.App.Payments
+	config.App.Payments.TopupMin = 10
+	config.App.Payments.TopupMax = 500
+	config.App.Payments.TopupDaily = 1000
+	t.Cleanup(func() { config.App.Payments = saved })
+	return user, fake
+}
+
+// createPendingIntent stores a pending intent with a provider reference.
+func createPendingIntent(t *testing.T, userID uint, amount models.Money, ref string) models.TopupIntent {
+	t.Helper()
+	intent := models.TopupIntent{
+		UserID:      userID,
+		Amount:      amount,
+		Status:      models.TopupPending,
+		Provider:    "fake",
+		ProviderRef: &ref,
+	}
+	if err := config.DB.Create(&intent).Error; err != nil {
+		t.Fatal(err)
+	}
+	return intent
+}
+
+// balanceAndLedger returns the user's credits and number of ledger rows.
+func balanceAndLedger(t *testing.T, userID uint) (models.Money, int64) {
+	t.Helper()
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	config.DB.Model(&models.CreditTransaction{}).Where("user_id = ?", userID).Count(&n)
+	return user.Credits, n
+}
+
+func TestSettleTopupIntentIsIdempotent(t *testing.T) {
+	user, _ := newTopupTest(t)
+	intent := createPendingIntent(t, user.ID, models.Credits(100), "ref_ok")
+
+	for i := 0; i < 3; i++ {
+		got, err := settleTopupIntent(intent.ID, payments.StatusSucceeded)
+		if err != nil {
+			t.Fatalf("settle #%d: %v", i+1, err)
+		}
+		if got.Status != models.TopupSucceeded {
+			t.Fatalf("settle #%d: status = %s", i+1, got.Status)
+		}
+	}
+	if credits, rows := balanceAndLedger(t, user.ID); credits != models.Credits(100) || rows != 1 {
+		t.Errorf("after three settles: credits = %s, ledger rows = %d; want 100 and 1", credits, rows)
+	}
+
+	failed := createPendingIntent(t, user.ID, models.Credits(50), "ref_failed")
+	if _, err := settleTopupIntent(failed.ID, payments.StatusFailed); err != nil {
+		t.Fatal(err)
+	}
+	got, err := settleTopupIntent(failed.ID, payments.StatusSucceeded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.TopupFailed {
+		t.Errorf("failed intent became %s", got.Status)
+	}
+	if credits, rows := balanceAndLedger(t, user.ID); credits != models.Credits(100) || rows != 1 {
+		t.Errorf("failed intent was credited: credits = %s, ledger rows = %d", credits, rows)
+	}
+}
+
+func TestCreateTopupIntentDailyLimit(t *testing.T) {
+	user, _ := newTopupTest(t)
+	createPendingIntent(t, user.ID, models.Credits(400), "ref_1")
+	createPendingIntent(t, user.ID, models.Credits(400), "ref_2")
+
+	tests := []struct {
+		amount string
+		want   int
+	}{
+		{"300", http.StatusBadRequest}, // 800 + 300 > 1000
+		{"200", http.StatusCreated},    // exactly at the limit
+		{"10", http.StatusBadRequest},  // the limit is now used up
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/wallet/topup", strings.NewReader(`{"amount":`+tt.amount+`}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", user.ID)
+
+		CreateTopupIntent(c)
+		if w.Code != tt.want {
+			t.Errorf("top-up of %s: status = %d, want %d (%s)", tt.amount, w.Code, tt.want, w.Body)
+		}
+	}
+
+	var n int64
+	config.DB.Model(&models.TopupIntent{}).Where("user_id = ?", user.ID).Count(&n)
+	if n != 3 {
+		t.Errorf("stored %d intents, want 3", n)
+	}
+}
+
+func TestPaymentWebhookAmount(t *testing.T) {
+	user, fake := newTopupTest(t)
+	intent := createPendingIntent(t, user.ID, models.Credits(100), "ref_webhook")
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"wrong amount", `{"reference":"ref_webhook","status":"succeeded","amount":1}`, http.StatusBadRequest},
+		{"no amount", `{"reference":"ref_webhook","status":"succeeded"}`, http.StatusBadRequest},
+		{"unknown reference", `{"reference":"ref_other","status":"succeeded","amount":10000}`, http.StatusNotFound},
+		{"matching amount", `{"reference":"ref_webhook","status":"succeeded","amount":` +
+			strconv.FormatInt(int64(intent.Amount), 10) + `}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/payments/webhook/fake", strings.NewReader(tt.body))
+		c.Request.Header.Set(payments.FakeSignatureHeader, fake.Sign([]byte(tt.body)))
+		c.Params = gin.Params{{Key: "provider", Value: "fake"}}
+
+		PaymentWebhook(c)
+		if w.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body)
+		}
+		if tt.want != http.StatusOK {
+			if credits, rows := balanceAndLedger(t, user.ID); credits != 0 || rows != 0 {
+				t.Fatalf("%s: user was credited %s", tt.name, credits)
+			}
+		}
+	}
+	if credits, rows := balanceAndLedger(t, user.ID); credits != intent.Amount || rows != 1 {
+		t.Errorf("after a valid webhook: credits = %s, ledger rows = %d; want %s and 1", credits, rows, intent.Amount)
+	}
+}
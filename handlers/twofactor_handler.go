@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// loginChallengeTTL is how long the user has to type their 2FA code.
+	loginChallengeTTL = 5 * time.Minute
+	// loginChallengeAttempts is how many wrong codes end a challenge.
+	loginChallengeAttempts = 5
+	recoveryCodeCount      = 10
+)
+
+var (
+	errChallengeInvalid = errors.New("login challenge is invalid or expired")
+	errLoginBlocked     = errors.New("login is temporarily blocked")
+	errSecondFactor     = errors.New("invalid 2FA code")
+	err2FARequired      = errors.New("2FA is required for this role")
+)
+
+// createLoginChallenge stores a challenge for a user who passed the password
+// check and returns the plain token for the client.
+func createLoginChallenge(user models.User) (string, time.Time, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	exp := time.Now().Add(loginChallengeTTL)
+	err = config.DB.Create(&models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: exp,
+	}).Error
+	return plain, exp, err
+}
+
+// verifySecondFactorTx checks a TOTP code or, when code is empty, a recovery
+// code for a locked user row. Used codes can't be used again. It reports
+// whether a recovery code was consumed.
+func verifySecondFactorTx(tx *gorm.DB, user *models.User, code, recovery string) (bool, error) {
+	if !user.TOTPEnabled {
+		return false, errSecondFactor
+	}
+	if code != "" {
+		step, ok := auth.VerifyTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, errSecondFactor
+		}
+		user.TOTPLastStep = step
+		return false, tx.Model(user).Update("totp_last_step", step).Error
+	}
+	if recovery == "" {
+		return false, errSecondFactor
+	}
+	res := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashRecoveryCode(recovery)).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, errSecondFactor
+	}
+	return true, nil
+}
+
+// replaceRecoveryCodesTx discards the user's recovery codes and returns a
+// fresh set. The plain codes are only ever shown in this response.
+func replaceRecoveryCodesTx(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: auth.HashRecoveryCode(code)}
+	}
+	return codes, tx.Create(&rows).Error
+}
+
+func remainingRecoveryCodes(userID uint) int64 {
+	var n int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n)
+	return n
+}
+
+// LoginTwoFactor finishes a login that Login answered with a challenge. The
+// client sends the challenge token with either a TOTP code or a recovery code.
+func LoginTwoFactor(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาส่ง challenge_token และรหัส 2FA"})
+		return
+	}
+
+	var (
+		pair         tokenPair
+		user         models.User
+		usedRecovery bool
+		failed       bool
+		blockedUntil time.Time
+	)
+	ip := c.ClientIP()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var ch models.LoginChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", auth.HashToken(input.ChallengeToken)).
+			First(&ch).Error; err != nil {
+			return errChallengeInvalid
+		}
+		if ch.UsedAt != nil || time.Now().After(ch.ExpiresAt) || ch.Attempts >= loginChallengeAttempts {
+			return errChallengeInvalid
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, ch.UserID).Error; err != nil {
+			return errChallengeInvalid
+		}
+		// Wrong codes count against the same per-username/IP budget as
+		// passwords, so fresh challenges can't be used to keep guessing.
+		if until, blocked := loginBlockedUntil(user.Username, ip); blocked {
+			blockedUntil = until
+			return errLoginBlocked
+		}
+
+		var err error
+		usedRecovery, err = verifySecondFactorTx(tx, &user, input.Code, input.RecoveryCode)
+		if errors.Is(err, errSecondFactor) {
+			// Count the attempt and commit, so retries are limited.
+			failed = true
+			return tx.Model(&ch).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&ch).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		pair, err = startTokenFamilyTx(c, tx, user, true)
+		return err
+	})
+
+	if errors.Is(err, errChallengeInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "การยืนยันหมดอายุหรือไม่ถูกต้อง กรุณาเข้าสู่ระบบใหม่"})
+		return
+	}
+	if errors.Is(err, errLoginBlocked) {
+		respondLoginBlocked(c, blockedUntil)
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] 2FA login failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถออก Token ได้"})
+		return
+	}
+	if failed {
+		recordLoginFailure(user.Username, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "รหัส 2FA ไม่ถูกต้อง"})
+		return
+	}
+	if err := clearLoginFailures(config.DB, user.Username); err != nil {
+		log.Printf("[AUTH] clear login failures failed (uid=%d): %v", user.ID, err)
+	}
+
+	resp := pair.response(user)
+	if usedRecovery {
+		log.Printf("[AUTH] recovery code used (uid=%d)", user.ID)
+		resp["recovery_codes_remaining"] = remainingRecoveryCodes(user.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetTwoFactorStatus reports whether 2FA is on and whether the user's role
+// requires it.
+func GetTwoFactorStatus(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+	role, _ := models.LoadRole(config.DB, user.Role)
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"required":                 role.Require2FA,
+		"recovery_codes_remaining": remainingRecoveryCodes(user.ID),
+	})
+}
+
+// EnrollTwoFactor starts 2FA setup: it creates a new secret and returns the
+// otpauth URI for the authenticator app. 2FA stays off until
+// ConfirmTwoFactor receives a valid code.
+func EnrollTwoFactor(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลผู้ใช้"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "เปิดใช้ 2FA อยู่แล้ว"})
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง 2FA ไม่สำเร็จ"})
+		return
+	}
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง 2FA ไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(config.App.Auth.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor turns 2FA on once the user proves their app works. It
+// returns the recovery codes and a new token pair for this device that
+// counts as a 2FA login.
+func ConfirmTwoFactor(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+	familyID := c.GetString("family_id")
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณากรอกรหัสจากแอป"})
+		return
+	}
+
+	var (
+		user  models.User
+		codes []string
+		pair  tokenPair
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.TOTPEnabled || user.TOTPSecret == "" {
+			return errSecondFactor
+		}
+		step, ok := auth.VerifyTOTP(user.TOTPSecret, input.Code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return errSecondFactor
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		user.TOTPEnabled = true
+
+		var err error
+		if codes, err = replaceRecoveryCodesTx(tx, user.ID); err != nil {
+			return err
+		}
+		// Swap this device's login for one that counts as 2FA.
+		if err := revokeFamily(tx, familyID, "2fa enabled"); err != nil {
+			return err
+		}
+		pair, err = startTokenFamilyTx(c, tx, user, true)
+		return err
+	})
+
+	if errors.Is(err, errSecondFactor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสไม่ถูกต้อง หรือยังไม่ได้เริ่มตั้งค่า 2FA"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] 2FA confirm failed (uid=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เปิดใช้ 2FA ไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[AUTH] 2FA enabled (uid=%d)", user.ID)
+	resp := pair.response(user)
+	resp["message"] = "เปิดใช้ 2FA เรียบร้อย กรุณาเก็บ recovery codes ไว้ในที่ปลอดภัย"
+	resp["recovery_codes"] = codes
+	c.JSON(http.StatusOK, resp)
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current
+// code. Users whose role requires 2FA cannot turn it off.
+func DisableTwoFactor(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var input struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณากรอกรหัสผ่านและรหัส 2FA"})
+		return
+	}
+
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			return errSecondFactor
+		}
+		role, err := models.LoadRole(tx, user.Role)
+		if err != nil {
+			return err
+		}
+		if role.Require2FA {
+			return err2FARequired
+		}
+		if _, err := verifySecondFactorTx(tx, &user, input.Code, input.RecoveryCode); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    nil,
+			"totp_last_step": 0,
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, errSecondFactor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผ่านหรือรหัส 2FA ไม่ถูกต้อง"})
+		return
+	case errors.Is(err, err2FARequired):
+		c.JSON(http.StatusConflict, gin.H{"error": "role ของคุณบังคับใช้ 2FA จึงปิดไม่ได้"})
+		return
+	case err != nil:
+		log.Printf("[AUTH] 2FA disable failed (uid=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ปิด 2FA ไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[AUTH] 2FA disabled (uid=%d)", user.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "ปิด 2FA เรียบร้อย"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking
+// a current TOTP code.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	userID := val.(uint)
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณากรอกรหัสจากแอป"})
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if _, err := verifySecondFactorTx(tx, &user, input.Code, ""); err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodesTx(tx, user.ID)
+		return err
+	})
+
+	if errors.Is(err, errSecondFactor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัส 2FA ไม่ถูกต้อง"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] recovery code regeneration failed (uid=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง recovery codes ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// SetRoleTwoFactor lets an admin require 2FA for a staff role. Logins
+// without 2FA keep working but lose that role's permissions until the user
+// enrolls and signs in again.
+func SetRoleTwoFactor(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	adminID := val.(uint)
+
+	var input struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาส่ง required (true/false)"})
+		return
+	}
+
+	name := c.Param("name")
+	if name == models.RoleUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "บังคับ 2FA ได้เฉพาะ role ของทีมงาน"})
+		return
+	}
+
+	var role models.Role
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&role, "name = ?", name).Error; err != nil {
+			return err
+		}
+		if role.Require2FA == *input.Required {
+			return nil
+		}
+		if err := tx.Model(&role).Update("require_2fa", *input.Required).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, &adminID, "role.2fa.changed", "role", 0, map[string]interface{}{
+			"role":     role.Name,
+			"required": *input.Required,
+		})
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ role"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] role 2FA change failed (role=%s): %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เปลี่ยนการตั้งค่า 2FA ไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[ADMIN] role %s require_2fa → %v by staff #%d", name, *input.Required, adminID)
+	c.JSON(http.StatusOK, gin.H{"name": role.Name, "require_2fa": *input.Required})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Bannawat01/ec-space/models"
+)
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.RecoveryCode{})
+	alice := models.User{Username: "alice", Email: "alice@example.com", TOTPEnabled: true}
+	bob := models.User{Username: "bob", Email: "bob@example.com", TOTPEnabled: true}
+	db.Create(&alice)
+	db.Create(&bob)
+
+	codes, err := replaceRecoveryCodesTx(db, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	if _, err := verifySecondFactorTx(db, &bob, "", codes[0]); !errors.Is(err, errSecondFactor) {
+		t.Errorf("another user's code: err = %v, want errSecondFactor", err)
+	}
+	used, err := verifySecondFactorTx(db, &alice, "", codes[0])
+	if err != nil || !used {
+		t.Fatalf("first use = %v, %v; want true, nil", used, err)
+	}
+	if _, err := verifySecondFactorTx(db, &alice, "", codes[0]); !errors.Is(err, errSecondFactor) {
+		t.Errorf("second use: err = %v, want errSecondFactor", err)
+	}
+	// The typed form doesn't matter, the code is still spent.
+	if _, err := verifySecondFactorTx(db, &alice, "", " "+codes[0][:5]+codes[0][6:]+" "); !errors.Is(err, errSecondFactor) {
+		t.Errorf("reuse in another form: err = %v, want errSecondFactor", err)
+	}
+	if used, err := verifySecondFactorTx(db, &alice, "", codes[1]); err != nil || !used {
+		t.Errorf("next code = %v, %v; want true, nil", used, err)
+	}
+
+	var left int64
+	db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", alice.ID).Count(&left)
+	if left != recoveryCodeCount-2 {
+		t.Errorf("%d unused codes left, want %d", left, recoveryCodeCount-2)
+	}
+
+	// New codes replace the old ones, spent or not.
+	if _, err := replaceRecoveryCodesTx(db, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifySecondFactorTx(db, &alice, "", codes[2]); !errors.Is(err, errSecondFactor) {
+		t.Errorf("replaced code: err = %v, want errSecondFactor", err)
+	}
+
+	alice.TOTPEnabled = false
+	if _, err := verifySecondFactorTx(db, &alice, "", codes[3]); !errors.Is(err, errSecondFactor) {
+		t.Errorf("2FA disabled: err = %v, want errSecondFactor", err)
+	}
+}
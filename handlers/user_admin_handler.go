@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errUnknownRole    = errors.New("unknown role")
+	errLastAdmin      = errors.New("cannot remove the last superuser")
+	errSelfDemotion   = errors.New("staff cannot change their own role")
+	errPasswordLength = errors.New("password must be at least 8 characters")
+)
+
+// setUserRoleTx changes a user's role inside tx, ends their current logins so
+// the new role takes effect immediately, and writes an audit record.
+// actorID is nil when called from the CLI.
+func setUserRoleTx(tx *gorm.DB, userID uint, role string, actorID *uint, reason string) (models.User, error) {
+	var user models.User
+	target, err := models.LoadRole(tx, role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, errUnknownRole
+	}
+	if err != nil {
+		return user, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return user, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if actorID != nil && *actorID == user.ID {
+		return user, errSelfDemotion
+	}
+	current, err := models.LoadRole(tx, user.Role)
+	if err != nil {
+		return user, err
+	}
+	if current.IsSuperuser && !target.IsSuperuser {
+		// Lock every superuser row so two concurrent demotions can't both pass.
+		var supers []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role IN (?)", tx.Model(&models.Role{}).Select("name").Where("is_superuser")).
+			Find(&supers).Error; err != nil {
+			return user, err
+		}
+		if len(supers) <= 1 {
+			return user, errLastAdmin
+		}
+	}
+
+	from := user.Role
+	if err := tx.Model(&user).Update("role", role).Error; err != nil {
+		return user, err
+	}
+	if err := revokeUserFamilies(tx, user.ID, "role changed"); err != nil {
+		return user, err
+	}
+	if err := writeAudit(tx, actorID, "user.role.changed", "user", user.ID, map[string]interface{}{
+		"from":   from,
+		"to":     role,
+		"reason": reason,
+	}); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// GetRoles lists every role with the permissions it grants.
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("code")
+	}).Order("name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงข้อมูล role ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// UpdateUserRole assigns one of the roles in the roles table to a user.
+// Staff cannot change their own role and the last superuser cannot be demoted.
+func UpdateUserRole(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	adminID := val.(uint)
+
+	var input struct {
+		Role   string `json:"role" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผู้ใช้ไม่ถูกต้อง"})
+		return
+	}
+
+	var user models.User
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = setUserRoleTx(tx, uint(targetID), input.Role, &adminID, input.Reason)
+		return err
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้ใช้"})
+		return
+	case errors.Is(err, errUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ไม่รู้จัก role '%s'", input.Role)})
+		return
+	case errors.Is(err, errSelfDemotion), errors.Is(err, errLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("[ADMIN] role change failed (uid=%d): %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เปลี่ยน role ไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[ADMIN] user #%d role → %s by staff #%d", user.ID, input.Role, adminID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "เปลี่ยน role สำเร็จ",
+		"id":       user.ID,
+		"username": user.Username,
+		"role":     input.Role,
+	})
+}
+
+// UnlockUser clears the failed-login lockout on a user's account.
+func UnlockUser(c *gin.Context) {
+	val, _ := c.Get("user_id")
+	adminID := val.(uint)
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสผู้ใช้ไม่ถูกต้อง"})
+		return
+	}
+
+	var user models.User
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, targetID).Error; err != nil {
+			return err
+		}
+		if err := clearLoginFailures(tx, user.Username); err != nil {
+			return err
+		}
+		return writeAudit(tx, &adminID, "user.unlocked", "user", user.ID, map[string]interface{}{})
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้ใช้"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] unlock failed (uid=%d): %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ปลดล็อกบัญชีไม่สำเร็จ"})
+		return
+	}
+
+	log.Printf("[ADMIN] user #%d unlocked by staff #%d", user.ID, adminID)
+	c.JSON(http.StatusOK, gin.H{"message": "ปลดล็อกบัญชีเรียบร้อย", "id": user.ID, "username": user.Username})
+}
+
+// ProvisionAdmin is used by the `admin` CLI command. It promotes an existing
+// account to superuser, or creates a new superuser account when no user has
+// that username.
+// It returns true when a new account was created.
+func ProvisionAdmin(username, email, password string) (models.User, bool, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return models.User{}, false, errors.New("username is required")
+	}
+
+	var user models.User
+	created := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("username = ?", username).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if strings.TrimSpace(email) == "" {
+				return errors.New("email is required to create a new admin")
+			}
+			if len(password) < 8 {
+				return errPasswordLength
+			}
+			hashed, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+			if err != nil {
+				return err
+			}
+			user = models.User{
+				Username: username,
+				Email:    email,
+				Password: string(hashed),
+				Role:     models.RoleSuperuser,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			created = true
+			return writeAudit(tx, nil, "user.role.changed", "user", user.ID, map[string]interface{}{
+				"from":   "",
+				"to":     models.RoleSuperuser,
+				"reason": "created by admin bootstrap command",
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		user, err = setUserRoleTx(tx, user.ID, models.RoleSuperuser, nil, "promoted by admin bootstrap command")
+		return err
+	})
+	return user, created, err
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const variantMaxOptions = 5
+
+var (
+	skuPattern          = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]{0,63}$`)
+	variantOptionName   = regexp.MustCompile(`^[a-z][a-z0-9_]{0,29}$`)
+	errLastLiveVariant  = errors.New("last live variant")
+	errVariantSKUTaken  = errors.New("sku taken")
+	errVariantDuplicate = errors.New("duplicate options")
+)
+
+// variantOption is one row of the variant matrix: an option name and every
+// value the weapon's variants use for it.
+type variantOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// variantMatrix lists the options of a weapon's variants, names sorted and
+// values in the order the variants were created.
+func variantMatrix(variants []models.WeaponVariant) []variantOption {
+	index := map[string]int{}
+	matrix := []variantOption{}
+	seen := map[string]bool{}
+	for _, v := range variants {
+		for _, name := range v.Options.Names() {
+			i, ok := index[name]
+			if !ok {
+				i = len(matrix)
+				index[name] = i
+				matrix = append(matrix, variantOption{Name: name, Values: []string{}})
+			}
+			if value := v.Options[name]; !seen[name+"\x00"+value] {
+				seen[name+"\x00"+value] = true
+				matrix[i].Values = append(matrix[i].Values, value)
+			}
+		}
+	}
+	sort.SliceStable(matrix, func(i, j int) bool { return matrix[i].Name < matrix[j].Name })
+	return matrix
+}
+
+// defaultVariant is the single variant a weapon gets when it is created.
+func defaultVariant(w models.Weapon, stock int) models.WeaponVariant {
+	return models.WeaponVariant{
+		WeaponID: w.ID,
+		SKU:      "W" + strconv.FormatUint(uint64(w.ID), 10),
+		Options:  models.VariantOptions{},
+		Stock:    stock,
+	}
+}
+
+// variantInput is the body of POST /api/admin/weapons/:id/variants and
+// PATCH /api/admin/variants/:id. On PATCH only the fields sent change;
+// "price": null removes the price override.
+type variantInput struct {
+	SKU     *string           `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price   json.RawMessage   `json:"price"`
+	Stock   *int              `json:"stock"`
+}
+
+// apply copies the input onto v and checks it against the weapon's other
+// live variants: they must all use the same option names, and no two may
+// have the same values.
+func (in variantInput) apply(tx *gorm.DB, v *models.WeaponVariant) error {
+	if in.SKU != nil {
+		v.SKU = strings.ToUpper(strings.TrimSpace(*in.SKU))
+	}
+	if in.Options != nil {
+		opts := models.VariantOptions{}
+		for name, value := range in.Options {
+			name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+			if !variantOptionName.MatchString(name) {
+				return fmt.Errorf("ชื่อตัวเลือก %q ต้องเป็น a-z, 0-9 หรือ _ ไม่เกิน 30 ตัวอักษร", name)
+			}
+			if value == "" || utf8.RuneCountInString(value) > 50 {
+				return fmt.Errorf("ค่าของตัวเลือก %s ต้องมี 1-50 ตัวอักษร", name)
+			}
+			opts[name] = value
+		}
+		if len(opts) > variantMaxOptions {
+			return fmt.Errorf("มีตัวเลือกได้ไม่เกิน %d อย่าง", variantMaxOptions)
+		}
+		v.Options = opts
+	}
+	if len(in.Price) > 0 {
+		if bytes.Equal(bytes.TrimSpace(in.Price), []byte("null")) {
+			v.Price = nil
+		} else {
+			var p models.Money
+			if err := json.Unmarshal(in.Price, &p); err != nil || p < 0 {
+				return errors.New("ราคาไม่ถูกต้อง")
+			}
+			v.Price = &p
+		}
+	}
+	if in.Stock != nil {
+		if *in.Stock < 0 {
+			return errors.New("สต็อกต้องไม่ติดลบ")
+		}
+		v.Stock = *in.Stock
+	}
+	if v.Options == nil {
+		v.Options = models.VariantOptions{}
+	}
+
+	if !skuPattern.MatchString(v.SKU) {
+		return errors.New("SKU ต้องเป็น A-Z, 0-9, . _ - ไม่เกิน 64 ตัวอักษร")
+	}
+	var taken int64
+	if err := tx.Unscoped().Model(&models.WeaponVariant{}).
+		Where("sku = ? AND id <> ?", v.SKU, v.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errVariantSKUTaken
+	}
+
+	var siblings []models.WeaponVariant
+	if err := tx.Where("weapon_id = ? AND id <> ?", v.WeaponID, v.ID).Find(&siblings).Error; err != nil {
+		return err
+	}
+	names := strings.Join(v.Options.Names(), ",")
+	for _, s := range siblings {
+		if strings.Join(s.Options.Names(), ",") != names {
+			return fmt.Errorf("ทุกรุ่นของอาวุธนี้ต้องมีตัวเลือกเดียวกัน (%s)", strings.Join(s.Options.Names(), ", "))
+		}
+		same := true
+		for k, val := range v.Options {
+			if s.Options[k] != val {
+				same = false
+				break
+			}
+		}
+		if same {
+			return errVariantDuplicate
+		}
+	}
+	return nil
+}
+
+// respondVariantError maps the errors of the variant handlers to responses.
+// invalid is the validation error from variantInput.apply, if any.
+func respondVariantError(c *gin.Context, err, invalid error, action string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรุ่นสินค้า"})
+	case errors.Is(err, errVariantSKUTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "SKU นี้ถูกใช้แล้ว"})
+	case errors.Is(err, errVariantDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "มีรุ่นที่ใช้ตัวเลือกชุดนี้อยู่แล้ว"})
+	case errors.Is(err, errLastLiveVariant):
+		c.JSON(http.StatusConflict, gin.H{"error": "อาวุธต้องมีอย่างน้อย 1 รุ่น ให้เก็บตัวอาวุธเข้าคลังแทน"})
+	case invalid != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+	default:
+		log.Printf("[ADMIN] variant %s failed: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกรุ่นสินค้าไม่สำเร็จ"})
+	}
+}
+
+// CreateVariant - Admin adds a variant to a weapon
+func CreateVariant(c *gin.Context) {
+	var input variantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var weapon models.Weapon
+	if err := config.DB.First(&weapon, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอาวุธ"})
+		return
+	}
+
+	variant := models.WeaponVariant{WeaponID: weapon.ID}
+	var invalid error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if invalid = input.apply(tx, &variant); invalid != nil {
+			return invalid
+		}
+		return tx.Create(&variant).Error
+	})
+	if err != nil {
+		respondVariantError(c, err, invalid, "create")
+		return
+	}
+	variant.UnitPrice = variant.PriceFor(weapon)
+	c.JSON(http.StatusCreated, gin.H{"message": "เพิ่มรุ่นสินค้าสำเร็จ!", "variant": variant})
+}
+
+// UpdateVariant - Admin edits a variant's SKU, options, price or stock
+func UpdateVariant(c *gin.Context) {
+	var input variantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+
+	var (
+		variant models.WeaponVariant
+		weapon  models.Weapon
+		invalid error
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&variant, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().First(&weapon, variant.WeaponID).Error; err != nil {
+			return err
+		}
+		if invalid = input.apply(tx, &variant); invalid != nil {
+			return invalid
+		}
+		return tx.Save(&variant).Error
+	})
+	if err != nil {
+		respondVariantError(c, err, invalid, "update")
+		return
+	}
+	variant.UnitPrice = variant.PriceFor(weapon)
+	c.JSON(http.StatusOK, gin.H{"message": "อัปเดตสำเร็จ!", "variant": variant})
+}
+
+// DeleteVariant - Admin archives a variant. Past orders keep pointing at it;
+// it is taken out of every cart. A weapon's last live variant can't go.
+func DeleteVariant(c *gin.Context) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var variant models.WeaponVariant
+		if err := tx.First(&variant, c.Param("id")).Error; err != nil {
+			return err
+		}
+		var live int64
+		if err := tx.Model(&models.WeaponVariant{}).Where("weapon_id = ?", variant.WeaponID).Count(&live).Error; err != nil {
+			return err
+		}
+		if live <= 1 {
+			return errLastLiveVariant
+		}
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&variant).Error
+	})
+	if err != nil {
+		respondVariantError(c, err, nil, "archive")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "เก็บรุ่นสินค้าเข้าคลังเรียบร้อย"})
+}
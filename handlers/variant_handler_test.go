@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVariantInputApply(t *testing.T) {
+	db := useTestDB(t, &models.WeaponVariant{})
+	price := models.Money(120000)
+	existing := []models.WeaponVariant{
+		{WeaponID: 1, SKU: "W1-BLK", Options: models.VariantOptions{"color": "black"}, Stock: 3},
+		{WeaponID: 1, SKU: "W1-RED", Options: models.VariantOptions{"color": "red"}, Price: &price, Stock: 1},
+		{WeaponID: 2, SKU: "W2-OLD", Options: models.VariantOptions{}},
+	}
+	for i := range existing {
+		if err := db.Create(&existing[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An archived variant keeps its SKU: past orders still point at it.
+	if err := db.Delete(&existing[2]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+	tests := []struct {
+		name    string
+		target  models.WeaponVariant
+		in      variantInput
+		wantErr error  // matched with errors.Is
+		wantMsg string // prefix of a validation error
+		check   func(t *testing.T, v models.WeaponVariant)
+	}{
+		{
+			name:   "new variant",
+			target: models.WeaponVariant{WeaponID: 1},
+			in:     variantInput{SKU: str(" w1-blu "), Options: map[string]string{" Color ": " blue "}, Stock: num(4)},
+			check: func(t *testing.T, v models.WeaponVariant) {
+				if v.SKU != "W1-BLU" || v.Options["color"] != "blue" || v.Stock != 4 {
+					t.Errorf("variant = %+v", v)
+				}
+			},
+		},
+		{
+			name:    "mismatched option names",
+			target:  models.WeaponVariant{WeaponID: 1},
+			in:      variantInput{SKU: str("W1-XL"), Options: map[string]string{"size": "xl"}},
+			wantMsg: "ทุกรุ่นของอาวุธนี้ต้องมีตัวเลือกเดียวกัน",
+		},
+		{
+			name:    "extra option name",
+			target:  models.WeaponVariant{WeaponID: 1},
+			in:      variantInput{SKU: str("W1-BLK-XL"), Options: map[string]string{"color": "black", "size": "xl"}},
+			wantMsg: "ทุกรุ่นของอาวุธนี้ต้องมีตัวเลือกเดียวกัน",
+		},
+		{
+			name:    "duplicate option set",
+			target:  models.WeaponVariant{WeaponID: 1},
+			in:      variantInput{SKU: str("W1-BLK2"), Options: map[string]string{"color": "black"}},
+			wantErr: errVariantDuplicate,
+		},
+		{
+			name:    "sku of a live variant",
+			target:  models.WeaponVariant{WeaponID: 1},
+			in:      variantInput{SKU: str("w1-red"), Options: map[string]string{"color": "green"}},
+			wantErr: errVariantSKUTaken,
+		},
+		{
+			name:    "sku of an archived variant",
+			target:  models.WeaponVariant{WeaponID: 1},
+			in:      variantInput{SKU: str("W2-OLD"), Options: map[string]string{"color": "green"}},
+			wantErr: errVariantSKUTaken,
+		},
+		{
+			name:   "keeping its own sku",
+			target: existing[0],
+			in:     variantInput{SKU: str("W1-BLK"), Stock: num(9)},
+			check: func(t *testing.T, v models.WeaponVariant) {
+				if v.Stock != 9 {
+					t.Errorf("stock = %d, want 9", v.Stock)
+				}
+			},
+		},
+		{
+			name:    "negative stock",
+			target:  existing[0],
+			in:      variantInput{Stock: num(-1)},
+			wantMsg: "สต็อกต้องไม่ติดลบ",
+		},
+		{
+			name:    "negative price",
+			target:  existing[0],
+			in:      variantInput{Price: json.RawMessage(`-1`)},
+			wantMsg: "ราคาไม่ถูกต้อง",
+		},
+		{
+			name:   "price null clears the override",
+			target: existing[1],
+			in:     variantInput{Price: json.RawMessage(` null `)},
+			check: func(t *testing.T, v models.WeaponVariant) {
+				if v.Price != nil {
+					t.Errorf("price = %s, want nil", *v.Price)
+				}
+			},
+		},
+		{
+			name:   "price left out keeps the override",
+			target: existing[1],
+			in:     variantInput{Stock: num(2)},
+			check: func(t *testing.T, v models.WeaponVariant) {
+				if v.Price == nil || *v.Price != price {
+					t.Errorf("price = %v, want %s", v.Price, price)
+				}
+			},
+		},
+		{
+			name:   "option value of 50 Thai characters",
+			target: models.WeaponVariant{WeaponID: 3, SKU: "W3-TH"},
+			in:     variantInput{Options: map[string]string{"color": strings.Repeat("ก", 50)}},
+		},
+		{
+			name:    "option value too long",
+			target:  models.WeaponVariant{WeaponID: 3, SKU: "W3-TH"},
+			in:      variantInput{Options: map[string]string{"color": strings.Repeat("ก", 51)}},
+			wantMsg: "ค่าของตัวเลือก color",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.target
+			err := tt.in.apply(db, &v)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("apply() = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMsg != "":
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantMsg) {
+					t.Errorf("apply() = %v, want %q", err, tt.wantMsg)
+				}
+			case err != nil:
+				t.Errorf("apply() = %v", err)
+			case tt.check != nil:
+				tt.check(t, v)
+			}
+		})
+	}
+}
+
+func TestDeleteVariantKeepsLastLiveVariant(t *testing.T) {
+	db := useTestDB(t, &models.WeaponVariant{}, &models.CartItem{})
+	variants := []models.WeaponVariant{
+		{WeaponID: 1, SKU: "W1", Options: models.VariantOptions{"color": "black"}},
+		{WeaponID: 1, SKU: "W1-RED", Options: models.VariantOptions{"color": "red"}},
+	}
+	for i := range variants {
+		if err := db.Create(&variants[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&models.CartItem{UserID: 1, WeaponID: 1, VariantID: variants[1].ID, Quantity: 1})
+
+	deleteVariant := func(id uint) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/admin/variants/"+strconv.Itoa(int(id)), nil)
+		c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(id))}}
+		DeleteVariant(c)
+		return w.Code
+	}
+
+	if code := deleteVariant(variants[1].ID); code != http.StatusOK {
+		t.Fatalf("archive of one of two variants: status = %d, want 200", code)
+	}
+	var carts int64
+	config.DB.Model(&models.CartItem{}).Count(&carts)
+	if carts != 0 {
+		t.Errorf("%d cart items still hold the archived variant", carts)
+	}
+
+	if code := deleteVariant(variants[0].ID); code != http.StatusConflict {
+		t.Errorf("archive of the last live variant: status = %d, want 409", code)
+	}
+	var live int64
+	config.DB.Model(&models.WeaponVariant{}).Where("weapon_id = ?", 1).Count(&live)
+	if live != 1 {
+		t.Errorf("weapon has %d live variants, want 1", live)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adjustCreditsTx changes a locked user's balance by amount and appends the
+// matching ledger entry in the same transaction. Every write to
+// users.credits must go through here so the ledger stays complete.
+func adjustCreditsTx(tx *gorm.DB, user *models.User, amount models.Money, entry models.CreditTransaction) error {
+	newBalance := user.Credits + amount
+	if err := tx.Model(user).Update("credits", newBalance).Error; err != nil {
+		return err
+	}
+	user.Credits = newBalance
+
+	entry.UserID = user.ID
+	entry.Amount = amount
+	entry.BalanceAfter = newBalance
+	return tx.Create(&entry).Error
+}
+
+// GetWalletTransactions returns the authenticated user's credit statement,
+// newest first. Supports ?page= and ?page_size= (max 100).
+func GetWalletTransactions(c *gin.Context) {
+	val, exists := c.Get("user_id")
+	if !exists || val == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "กรุณาเข้าสู่ระบบใหม่"})
+		return
+	}
+	userID := val.(uint)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var total int64
+	config.DB.Model(&models.CreditTransaction{}).Where("user_id = ?", userID).Count(&total)
+
+	var entries []models.CreditTransaction
+	if err := config.DB.
+		Where("user_id = ?", userID).
+		Order("created_at desc, id desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถโหลดรายการเดินบัญชีได้"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":     entries,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
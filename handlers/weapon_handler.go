@@ -1,29 +1,499 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/Bannawat01/ec-space/config"
 	"github.com/Bannawat01/ec-space/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// GetWeapons - Get all weapons (public)
+const (
+	catalogDefaultPageSize = 24
+	catalogMaxPageSize     = 100
+
+	suggestDefaultLimit = 8
+	suggestMaxLimit     = 20
+)
+
+// catalogTSQuery turns the search text into a tsquery in both configs that
+// build weapons.search_vector. It takes the text twice.
+const catalogTSQuery = "(websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?))"
+
+// catalogPrice is the lowest price a weapon sells for: its cheapest live
+// variant, falling back to the weapon's price for variants without their own.
+// Price filters and sorts use it so they agree with what a buyer can pay.
+const catalogPrice = `(SELECT MIN(COALESCE(v.price, weapons.price)) FROM weapon_variants v
+	WHERE v.weapon_id = weapons.id AND v.archived_at IS NULL)`
+
+// catalogSorts maps the ?sort= values to ORDER BY clauses. id breaks ties so
+// pages never overlap. relevance is built per query, see catalogQuery.order.
+var catalogSorts = map[string]string{
+	"newest": "id DESC",
+	"price":  catalogPrice + " ASC, id ASC",
+	"-price": catalogPrice + " DESC, id DESC",
+	"power":  "power_level ASC, id ASC",
+	"-power": "power_level DESC, id DESC",
+	"name":   "lower(name) ASC, id ASC",
+	"-name":  "lower(name) DESC, id DESC",
+}
+
+// catalogQuery is a parsed GET /api/weapons request.
+type catalogQuery struct {
+	Types              []string
+	MinPrice, MaxPrice *models.Money
+	MinPower, MaxPower *int
+	InStock            bool
+	Q                  string
+	Sort               string
+	Page, PageSize     int
+
+	// Spec filters by attribute key, from spec[key]=a,b, spec_min[key]= and
+	// spec_max[key]=. resolveSpecs turns SpecValues into specDocs.
+	SpecValues       map[string][]string
+	SpecMin, SpecMax map[string]float64
+	specDocs         map[string][]string
+}
+
+// parseCatalogQuery reads the filters, sort and page from the query string.
+// type takes category slugs and may be repeated or comma-separated, as do
+// the spec[key] values.
+func parseCatalogQuery(c *gin.Context) (catalogQuery, error) {
+	q := catalogQuery{
+		Q:        strings.TrimSpace(c.Query("q")),
+		Sort:     c.Query("sort"),
+		Page:     1,
+		PageSize: catalogDefaultPageSize,
+	}
+	// Searches are ranked by relevance unless another order is asked for.
+	if q.Sort == "" || (q.Sort == "relevance" && q.Q == "") {
+		q.Sort = "newest"
+		if q.Q != "" {
+			q.Sort = "relevance"
+		}
+	}
+	for _, v := range c.QueryArray("type") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				q.Types = append(q.Types, t)
+			}
+		}
+	}
+	if _, ok := catalogSorts[q.Sort]; !ok && q.Sort != "relevance" {
+		return q, fmt.Errorf("sort ต้องเป็น relevance, newest, price, -price, power, -power, name หรือ -name")
+	}
+
+	money := func(name string) (*models.Money, error) {
+		v := c.Query(name)
+		if v == "" {
+			return nil, nil
+		}
+		m, err := models.ParseMoney(v)
+		if err != nil || m < 0 {
+			return nil, fmt.Errorf("%s ไม่ถูกต้อง", name)
+		}
+		return &m, nil
+	}
+	integer := func(name string) (*int, error) {
+		v := c.Query(name)
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s ต้องเป็นตัวเลข", name)
+		}
+		return &n, nil
+	}
+	var err error
+	if q.MinPrice, err = money("min_price"); err != nil {
+		return q, err
+	}
+	if q.MaxPrice, err = money("max_price"); err != nil {
+		return q, err
+	}
+	if q.MinPower, err = integer("min_power"); err != nil {
+		return q, err
+	}
+	if q.MaxPower, err = integer("max_power"); err != nil {
+		return q, err
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return q, fmt.Errorf("min_price ต้องไม่มากกว่า max_price")
+	}
+	if q.MinPower != nil && q.MaxPower != nil && *q.MinPower > *q.MaxPower {
+		return q, fmt.Errorf("min_power ต้องไม่มากกว่า max_power")
+	}
+	if v := c.Query("in_stock"); v != "" {
+		if q.InStock, err = strconv.ParseBool(v); err != nil {
+			return q, fmt.Errorf("in_stock ต้องเป็น true หรือ false")
+		}
+	}
+
+	q.SpecValues = map[string][]string{}
+	for key, v := range c.QueryMap("spec") {
+		if !specKeyPattern.MatchString(key) {
+			return q, fmt.Errorf("spec[%s] ไม่ถูกต้อง", key)
+		}
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				q.SpecValues[key] = append(q.SpecValues[key], s)
+			}
+		}
+	}
+	bounds := func(name string) (map[string]float64, error) {
+		out := map[string]float64{}
+		for key, v := range c.QueryMap(name) {
+			f, err := strconv.ParseFloat(v, 64)
+			if !specKeyPattern.MatchString(key) || err != nil {
+				return nil, fmt.Errorf("%s[%s] ต้องเป็นตัวเลข", name, key)
+			}
+			out[key] = f
+		}
+		return out, nil
+	}
+	if q.SpecMin, err = bounds("spec_min"); err != nil {
+		return q, err
+	}
+	if q.SpecMax, err = bounds("spec_max"); err != nil {
+		return q, err
+	}
+
+	if v := c.Query("page"); v != "" {
+		if q.Page, err = strconv.Atoi(v); err != nil || q.Page < 1 {
+			return q, fmt.Errorf("page ต้องเป็นจำนวนเต็มตั้งแต่ 1")
+		}
+	}
+	if v := c.Query("page_size"); v != "" {
+		if q.PageSize, err = strconv.Atoi(v); err != nil || q.PageSize < 1 || q.PageSize > catalogMaxPageSize {
+			return q, fmt.Errorf("page_size ต้องอยู่ระหว่าง 1 ถึง %d", catalogMaxPageSize)
+		}
+	}
+	return q, nil
+}
+
+// resolveSpecs looks up the spec filters' attributes, which must be
+// filterable, and turns each spec[key] value into the JSON documents
+// weapons.specs is matched against. The same key may have a different type
+// in unrelated categories, so a value matches any of them.
+func (q *catalogQuery) resolveSpecs(db *gorm.DB) error {
+	keys := []string{}
+	for key := range q.SpecValues {
+		keys = append(keys, key)
+	}
+	for _, m := range []map[string]float64{q.SpecMin, q.SpecMax} {
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	var attrs []models.SpecAttribute
+	if err := db.Select("DISTINCT key, type").
+		Where("key IN ? AND filterable", keys).
+		Find(&attrs).Error; err != nil {
+		return err
+	}
+	types := map[string][]string{}
+	for _, a := range attrs {
+		types[a.Key] = append(types[a.Key], a.Type)
+	}
+
+	numeric := func(key string) bool {
+		for _, t := range types[key] {
+			if t == models.SpecNumber || t == models.SpecInteger {
+				return true
+			}
+		}
+		return false
+	}
+	for _, m := range []map[string]float64{q.SpecMin, q.SpecMax} {
+		for key := range m {
+			if !numeric(key) {
+				return fmt.Errorf("กรองช่วงของ spec %q ไม่ได้", key)
+			}
+		}
+	}
+
+	q.specDocs = map[string][]string{}
+	for key, values := range q.SpecValues {
+		if len(types[key]) == 0 {
+			return fmt.Errorf("กรองด้วย spec %q ไม่ได้", key)
+		}
+		for _, v := range values {
+			matched := false
+			for _, t := range types[key] {
+				var value interface{}
+				var err error
+				switch t {
+				case models.SpecNumber:
+					value, err = strconv.ParseFloat(v, 64)
+				case models.SpecInteger:
+					value, err = strconv.ParseInt(v, 10, 64)
+				case models.SpecBoolean:
+					value, err = strconv.ParseBool(v)
+				default:
+					value = v
+				}
+				if err != nil {
+					continue
+				}
+				doc, _ := json.Marshal(map[string]interface{}{key: value})
+				q.specDocs[key] = append(q.specDocs[key], string(doc))
+				matched = true
+			}
+			if !matched {
+				return fmt.Errorf("spec[%s] ไม่ถูกต้อง", key)
+			}
+		}
+	}
+	return nil
+}
+
+// filter applies every filter except type, so the type facet can show what
+// each type would add to the current results.
+func (q catalogQuery) filter(db *gorm.DB) *gorm.DB {
+	db = db.Model(&models.Weapon{})
+	if q.MinPrice != nil {
+		db = db.Where(catalogPrice+" >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		db = db.Where(catalogPrice+" <= ?", *q.MaxPrice)
+	}
+	if q.MinPower != nil {
+		db = db.Where("power_level >= ?", *q.MinPower)
+	}
+	if q.MaxPower != nil {
+		db = db.Where("power_level <= ?", *q.MaxPower)
+	}
+	if q.InStock {
+		db = db.Where("stock > 0")
+	}
+	if q.Q != "" {
+		// Full-text for whole words, ILIKE for Thai and partial words, and
+		// trigram word similarity so a typo in the name still matches.
+		like := "%" + escapeLike(q.Q) + "%"
+		db = db.Where("search_vector @@ "+catalogTSQuery+" OR name ILIKE ? OR description ILIKE ? OR ? <% name",
+			q.Q, q.Q, like, like, q.Q)
+	}
+	// Exact values use containment (@>) so the GIN index on specs applies;
+	// several values of one key match any of them.
+	for _, docs := range sortedSpecs(q.specDocs) {
+		match := db.Session(&gorm.Session{NewDB: true}).Where("specs @> ?", docs[0])
+		for _, doc := range docs[1:] {
+			match = match.Or("specs @> ?", doc)
+		}
+		db = db.Where(match)
+	}
+	// The CASE keeps the cast away from weapons whose value isn't a number.
+	const specNumber = "CASE WHEN jsonb_typeof(specs -> ?::text) = 'number' THEN (specs ->> ?::text)::numeric END"
+	for _, key := range sortedKeys(q.SpecMin) {
+		db = db.Where(specNumber+" >= ?", key, key, q.SpecMin[key])
+	}
+	for _, key := range sortedKeys(q.SpecMax) {
+		db = db.Where(specNumber+" <= ?", key, key, q.SpecMax[key])
+	}
+	return db
+}
+
+func (q catalogQuery) filterAll(db *gorm.DB) *gorm.DB {
+	db = q.filter(db)
+	if len(q.Types) > 0 {
+		// A parent category also matches the weapons in its subcategories.
+		db = db.Where(`type IN (
+			WITH RECURSIVE tree AS (
+				SELECT id, slug FROM categories WHERE slug IN ?
+				UNION
+				SELECT c.id, c.slug FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT slug FROM tree)`, q.Types)
+	}
+	return db
+}
+
+// order is the ORDER BY for the requested sort. Relevance combines the
+// full-text rank with how closely the name matches the search text.
+func (q catalogQuery) order() interface{} {
+	if q.Sort != "relevance" {
+		return catalogSorts[q.Sort]
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank_cd(search_vector, " + catalogTSQuery + ") + word_similarity(?, name) DESC, id DESC",
+		Vars:               []interface{}{q.Q, q.Q, q.Q},
+		WithoutParentheses: true,
+	}}
+}
+
+// sortedKeys and sortedSpecs walk the spec filters in key order so the same
+// request always builds the same SQL.
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedSpecs(m map[string][]string) [][]string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, m[k])
+	}
+	return out
+}
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// facetCount is one value of a facet and how many weapons have it.
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// GetWeapons - Search the catalog (public). Supports ?type=, ?min_price=,
+// ?max_price=, ?min_power=, ?max_power=, ?in_stock=true, ?q= (full-text,
+// ranked by relevance), ?spec[key]=, ?spec_min[key]=, ?spec_max[key]=,
+// ?sort= and ?page= / ?page_size= (max 100). The response carries the total and the
+// number of matches per type.
 func GetWeapons(c *gin.Context) {
-	var weapons []models.Weapon
-	config.DB.Find(&weapons)
-	c.JSON(http.StatusOK, weapons)
+	q, err := parseCatalogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := q.resolveSpecs(config.DB); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := q.filterAll(config.DB).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ค้นหาอาวุธไม่สำเร็จ"})
+		return
+	}
+
+	weapons := []models.Weapon{}
+	if err := q.filterAll(config.DB).
+		Preload("Category").
+		Order(q.order()).
+		Limit(q.PageSize).
+		Offset((q.Page - 1) * q.PageSize).
+		Find(&weapons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ค้นหาอาวุธไม่สำเร็จ"})
+		return
+	}
+
+	types := []facetCount{}
+	if err := q.filter(config.DB).
+		Select("type AS value, COUNT(*) AS count").
+		Group("type").
+		Order("count DESC, value ASC").
+		Scan(&types).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ค้นหาอาวุธไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":     weapons,
+		"page":      q.Page,
+		"page_size": q.PageSize,
+		"total":     total,
+		"facets":    gin.H{"type": types},
+	})
 }
 
-// GetWeapon - Get a single weapon by ID (public)
+// SuggestWeapons - Autocomplete for the search box (public). Returns up to
+// ?limit= (default 8, max 20) weapon names for ?q=: names starting with the
+// text first, then the closest by trigram similarity, so "plsma" still
+// offers "Plasma Rifle".
+func SuggestWeapons(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	limit := suggestDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > suggestMaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit ต้องอยู่ระหว่าง 1 ถึง %d", suggestMaxLimit)})
+			return
+		}
+		limit = n
+	}
+
+	type suggestion struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	suggestions := []suggestion{}
+	// One character matches too much to be useful.
+	if utf8.RuneCountInString(text) < 2 {
+		c.JSON(http.StatusOK, suggestions)
+		return
+	}
+
+	prefix := escapeLike(text) + "%"
+	like := "%" + escapeLike(text) + "%"
+	if err := config.DB.Model(&models.Weapon{}).
+		Select("id, name, type").
+		Where("name ILIKE ? OR ? <% name", like, text).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "name ILIKE ? DESC, word_similarity(?, name) DESC, lower(name) ASC",
+			Vars:               []interface{}{prefix, text},
+			WithoutParentheses: true,
+		}}).
+		Limit(limit).
+		Scan(&suggestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ค้นหาอาวุธไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// GetWeapon - Get a single weapon by ID (public), with its live variants,
+// the option matrix to pick one from and its spec sheet in ?lang=.
 func GetWeapon(c *gin.Context) {
 	id := c.Param("id")
 	var weapon models.Weapon
 
-	if err := config.DB.First(&weapon, id).Error; err != nil {
+	if err := config.DB.Preload("Category").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&weapon, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอาวุธ"})
 		return
 	}
+	for i := range weapon.Variants {
+		weapon.Variants[i].UnitPrice = weapon.Variants[i].PriceFor(weapon)
+	}
+	schema, err := categorySchema(config.DB, weapon.Type)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ดึงข้อมูลอาวุธไม่สำเร็จ"})
+		return
+	}
 
-	c.JSON(http.StatusOK, weapon)
+	c.JSON(http.StatusOK, struct {
+		models.Weapon
+		Options   []variantOption `json:"options"`
+		SpecSheet []specLine      `json:"spec_sheet"`
+	}{
+		Weapon:    weapon,
+		Options:   variantMatrix(weapon.Variants),
+		SpecSheet: specSheet(schema, weapon.Specs, c.DefaultQuery("lang", models.DefaultLanguage)),
+	})
 }
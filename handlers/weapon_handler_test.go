@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCatalogQuery(t *testing.T) {
+	money := func(m models.Money) *models.Money { return &m }
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string // prefix of the error message
+		check   func(t *testing.T, q catalogQuery)
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			check: func(t *testing.T, q catalogQuery) {
+				if q.Sort != "newest" || q.Page != 1 || q.PageSize != catalogDefaultPageSize {
+					t.Errorf("sort/page/page_size = %s/%d/%d", q.Sort, q.Page, q.PageSize)
+				}
+				if q.Types != nil || q.MinPrice != nil || q.InStock {
+					t.Errorf("unexpected filters: %+v", q)
+				}
+			},
+		},
+		{
+			name:  "search sorts by relevance",
+			query: "q=+plasma+",
+			check: func(t *testing.T, q catalogQuery) {
+				if q.Q != "plasma" || q.Sort != "relevance" {
+					t.Errorf("q/sort = %q/%s, want plasma/relevance", q.Q, q.Sort)
+				}
+			},
+		},
+		{
+			name:  "relevance without q falls back to newest",
+			query: "sort=relevance",
+			check: func(t *testing.T, q catalogQuery) {
+				if q.Sort != "newest" {
+					t.Errorf("sort = %s, want newest", q.Sort)
+				}
+			},
+		},
+		{
+			name:  "explicit sort with q",
+			query: "q=rifle&sort=-price",
+			check: func(t *testing.T, q catalogQuery) {
+				if q.Sort != "-price" {
+					t.Errorf("sort = %s, want -price", q.Sort)
+				}
+			},
+		},
+		{name: "unknown sort", query: "sort=cheapest", wantErr: "sort ต้องเป็น"},
+		{
+			name:  "comma-separated and repeated type",
+			query: "type=rifle,+blade+,&type=cannon&type=",
+			check: func(t *testing.T, q catalogQuery) {
+				if want := []string{"rifle", "blade", "cannon"}; !reflect.DeepEqual(q.Types, want) {
+					t.Errorf("types = %q, want %q", q.Types, want)
+				}
+			},
+		},
+		{
+			name:  "price range",
+			query: "min_price=10.5&max_price=2000",
+			check: func(t *testing.T, q catalogQuery) {
+				if !reflect.DeepEqual(q.MinPrice, money(1050)) || !reflect.DeepEqual(q.MaxPrice, money(200000)) {
+					t.Errorf("min/max price = %v/%v", q.MinPrice, q.MaxPrice)
+				}
+			},
+		},
+		{
+			name:  "equal min and max price",
+			query: "min_price=100&max_price=100",
+			check: func(t *testing.T, q catalogQuery) {
+				if *q.MinPrice != *q.MaxPrice {
+					t.Errorf("min/max price = %s/%s", *q.MinPrice, *q.MaxPrice)
+				}
+			},
+		},
+		{name: "min price above max", query: "min_price=500&max_price=100", wantErr: "min_price ต้องไม่มากกว่า max_price"},
+		{name: "negative price", query: "min_price=-1", wantErr: "min_price ไม่ถูกต้อง"},
+		{name: "price not a number", query: "max_price=abc", wantErr: "max_price ไม่ถูกต้อง"},
+		{name: "min power above max", query: "min_power=90&max_power=10", wantErr: "min_power ต้องไม่มากกว่า max_power"},
+		{name: "power not a number", query: "min_power=high", wantErr: "min_power ต้องเป็นตัวเลข"},
+		{name: "in_stock not a bool", query: "in_stock=maybe", wantErr: "in_stock ต้องเป็น true หรือ false"},
+		{
+			name:  "page bounds",
+			query: "page=3&page_size=100",
+			check: func(t *testing.T, q catalogQuery) {
+				if q.Page != 3 || q.PageSize != catalogMaxPageSize {
+					t.Errorf("page/page_size = %d/%d", q.Page, q.PageSize)
+				}
+			},
+		},
+		{name: "page zero", query: "page=0", wantErr: "page ต้องเป็นจำนวนเต็มตั้งแต่ 1"},
+		{name: "page not a number", query: "page=two", wantErr: "page ต้องเป็นจำนวนเต็มตั้งแต่ 1"},
+		{name: "page_size zero", query: "page_size=0", wantErr: "page_size ต้องอยู่ระหว่าง 1 ถึง 100"},
+		{name: "page_size above max", query: "page_size=101", wantErr: "page_size ต้องอยู่ระหว่าง 1 ถึง 100"},
+		{
+			name:  "spec values and bounds",
+			query: "spec[color]=red,+black&spec_min[range]=1.5&spec_max[range]=30&spec_max[weight]=-2",
+			check: func(t *testing.T, q catalogQuery) {
+				if want := map[string][]string{"color": {"red", "black"}}; !reflect.DeepEqual(q.SpecValues, want) {
+					t.Errorf("spec values = %v, want %v", q.SpecValues, want)
+				}
+				if want := map[string]float64{"range": 1.5}; !reflect.DeepEqual(q.SpecMin, want) {
+					t.Errorf("spec_min = %v, want %v", q.SpecMin, want)
+				}
+				if want := map[string]float64{"range": 30, "weight": -2}; !reflect.DeepEqual(q.SpecMax, want) {
+					t.Errorf("spec_max = %v, want %v", q.SpecMax, want)
+				}
+			},
+		},
+		{name: "spec_min not a number", query: "spec_min[range]=far", wantErr: "spec_min[range] ต้องเป็นตัวเลข"},
+		{name: "spec_max with a bad key", query: "spec_max[Range]=10", wantErr: "spec_max[Range] ต้องเป็นตัวเลข"},
+		{name: "spec with a bad key", query: "spec[a-b]=x", wantErr: "spec[a-b] ไม่ถูกต้อง"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/weapons?"+tt.query, nil)
+			q, err := parseCatalogQuery(c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("parseCatalogQuery(%q) = %v, want %q", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCatalogQuery(%q) = %v", tt.query, err)
+			}
+			tt.check(t, q)
+		})
+	}
+}
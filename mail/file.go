@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message to Dir as an .eml file instead of sending
+// it. Use it in development and tests to read the links that would have been
+// emailed.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer creates dir if needed and returns a FileMailer for it.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail: file driver needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mail: create %s: %w", dir, err)
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := validHeader(msg.To); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), sanitize(msg.To))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, format(m.From, msg), 0o600); err != nil {
+		return err
+	}
+	log.Printf("[MAIL] %q to %s written to %s", msg.Subject, msg.To, path)
+	return nil
+}
+
+// LogMailer prints messages to the server log. It is the default so a fresh
+// checkout works without any mail setup.
+type LogMailer struct {
+	From string
+}
+
+func (m LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("[MAIL] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func sanitize(s string) string {
+	out := []rune(s)
+	for i, r := range out {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,81 @@
+// Package mail sends the transactional emails the API needs (password reset,
+// email verification). Handlers talk to the Mailer interface; which
+// implementation is used comes from configuration.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a Message.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	mu      sync.RWMutex
+	current Mailer = LogMailer{}
+)
+
+// New builds the Mailer selected by cfg.Driver: "smtp", "file" or "log".
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTP, cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case "log", "":
+		return LogMailer{From: cfg.From}, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", cfg.Driver)
+	}
+}
+
+// SetDefault replaces the Mailer returned by Default.
+func SetDefault(m Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = m
+}
+
+// Default returns the Mailer configured at startup.
+func Default() Mailer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// format renders msg as an RFC 5322 message with CRLF line endings.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validHeader rejects values that could inject extra headers.
+func validHeader(v string) error {
+	if strings.ContainsAny(v, "\r\n") {
+		return fmt.Errorf("mail: header value contains a line break: %q", v)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"github.com/Bannawat01/ec-space/config"
+)
+
+// SMTPMailer sends mail through an SMTP server. net/smtp upgrades to TLS with
+// STARTTLS whenever the server offers it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer returns a Mailer for the given server. Authentication is only
+// used when a username is configured.
+func NewSMTPMailer(cfg config.SMTPConfig, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: from,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	for _, v := range []string{msg.To, msg.Subject} {
+		if err := validHeader(v); err != nil {
+			return err
+		}
+	}
+	// smtp.SendMail has no context support; run it in the background and
+	// stop waiting when ctx is done.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,29 +1,117 @@
 package main
 
 import (
+	"log"
 	"os"
 	"time" // เพิ่มอันนี้
 
+	"github.com/Bannawat01/ec-space/cli"
 	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/handlers"
+	"github.com/Bannawat01/ec-space/mail"
+	"github.com/Bannawat01/ec-space/migrations"
+	"github.com/Bannawat01/ec-space/oidc"
+	"github.com/Bannawat01/ec-space/payments"
 	"github.com/Bannawat01/ec-space/routes"
 	"github.com/gin-contrib/cors" // เพิ่มอันนี้ (ถ้าแดงให้รัน go get github.com/gin-contrib/cors)
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	config.InitDB()
+	// go run main.go mock-oidc [-addr :9999] — local identity provider for dev, needs no database
+	if len(os.Args) > 1 && os.Args[1] == "mock-oidc" {
+		if err := cli.MockOIDC(os.Args[2:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration:\n%v", err)
+	}
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	config.InitDB(cfg.Database)
+
+	// go run main.go migrate up | down [n] | status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := cli.Migrate(os.Args[2:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	if cfg.Database.AutoMigrate {
+		if _, err := migrations.Up(config.DB); err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+	} else if n, err := migrations.Pending(config.DB); err != nil {
+		log.Fatalf("❌ Could not read migration status: %v", err)
+	} else if n > 0 {
+		log.Fatalf("❌ %v (%d pending)", migrations.ErrPending, n)
+	}
 
-	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
-		os.Mkdir("uploads", os.ModePerm)
+	// go run main.go admin -username NAME [-email EMAIL] [-password PASS]
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := cli.Admin(os.Args[2:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	// go run main.go keys list | rotate [-now]
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := cli.Keys(os.Args[2:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	// Payment provider สำหรับการเติมเครดิต (fake ใช้สำหรับ dev/test เท่านั้น จึงไม่เปิดใน production)
+	if s := cfg.Payments.Stripe; s.SecretKey != "" {
+		payments.Register(payments.NewStripeProvider(s.SecretKey, cfg.Payments.WebhookSecret, s.Currency))
+	}
+	if !cfg.IsProduction() {
+		payments.Register(payments.NewFakeProvider(cfg.Payments.WebhookSecret, cfg.Payments.FakeAutoConfirm))
+	}
+	if err := payments.SetDefault(cfg.Payments.Provider); err != nil {
+		log.Fatalf("❌ Payment provider %q is not available", cfg.Payments.Provider)
+	}
+
+	// Mailer สำหรับลิงก์รีเซ็ตรหัสผ่าน / ยืนยันอีเมล (dev ใช้ log หรือ file)
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	mail.SetDefault(mailer)
+
+	// Key สำหรับเซ็น access token (HS256 ใช้ JWT_SECRET, RS256/EdDSA เก็บใน DB และหมุนเวียนอัตโนมัติ)
+	if err := handlers.LoadSigningKeys(); err != nil {
+		log.Fatalf("❌ Could not load JWT signing keys: %v", err)
+	}
+	go handlers.RunSigningKeyRotation()
+
+	// ผู้ให้บริการ OpenID Connect สำหรับปุ่มเข้าสู่ระบบด้วยบัญชีภายนอก
+	oidc.Configure(cfg.OIDC)
+
+	if _, err := os.Stat(cfg.Server.UploadDir); os.IsNotExist(err) {
+		os.Mkdir(cfg.Server.UploadDir, os.ModePerm)
 	}
 
 	r := gin.Default()
+	// ใช้ X-Forwarded-For เฉพาะจาก proxy ที่เชื่อถือ ไม่งั้น IP ปลอมได้ (การจำกัดการล็อกอินใช้ IP)
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("❌ Invalid trusted proxies: %v", err)
+	}
 
 	// --- ต้องวางก้อนนี้ "ก่อน" routes.SetupRoutes(r) ---
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:5174"},
+		AllowOrigins:     cfg.Server.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Accept"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Accept", "Idempotency-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -32,5 +120,5 @@ func main() {
 
 	routes.SetupRoutes(r)
 
-	r.Run(":8080")
-}
\ No newline at end of file
+	r.Run(":" + cfg.Server.Port)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyIDContextKey     = "api_key_id"
+	apiKeyScopesContextKey = "api_key_scopes"
+)
+
+// APIKeyScopes returns the scopes of the API key that authenticated the
+// request. ok is false for requests made with a login (JWT).
+func APIKeyScopes(c *gin.Context) (models.ScopeList, bool) {
+	v, ok := c.Get(apiKeyScopesContextKey)
+	if !ok {
+		return nil, false
+	}
+	return v.(models.ScopeList), true
+}
+
+// scopeAllows reports whether the request's credential may use scope. Logins
+// are not scoped; API keys only get what they were created with.
+func scopeAllows(c *gin.Context, scope string) bool {
+	scopes, isKey := APIKeyScopes(c)
+	return !isKey || scopes.Has(scope)
+}
+
+// AccountScope limits API keys on the customer routes: reads need
+// account:read and everything else account:write. Logins pass through.
+func AccountScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := models.ScopeAccountWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = models.ScopeAccountRead
+		}
+		if !scopeAllows(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API key นี้ไม่มีสิทธิ์ใช้งานส่วนนี้",
+				"scope": scope,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly refuses API keys on routes that manage the login itself
+// (logout, 2FA, API keys), so a leaked key can't be used to entrench itself.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := APIKeyScopes(c); isKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "ส่วนนี้ต้องเข้าสู่ระบบด้วยรหัสผ่าน ใช้ API key ไม่ได้"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,15 +1,24 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Bannawat01/ec-space/auth"
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5" // ต้องเป็น v5 ตามที่คุณลงไว้
 )
 
-// AuthMiddleware รับ jwtKey มาจาก main หรือ config
-func AuthMiddleware(jwtKey []byte) gin.HandlerFunc {
+// touchInterval limits how often a session's last_seen_at or a key's
+// last_used_at is written.
+const touchInterval = time.Minute
+
+// AuthMiddleware รับ keyring ที่ใช้ตรวจลายเซ็น access token (alg ต้องตรงกับ key ใน keyring เท่านั้น)
+// รับได้ทั้ง access token (JWT) และ API key (ขึ้นต้นด้วย ecs_) ใน header เดียวกัน
+func AuthMiddleware(keys *auth.Keyring) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -19,23 +28,77 @@ func AuthMiddleware(jwtKey []byte) gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		if auth.IsAPIKey(tokenString) {
+			authenticateAPIKey(c, tokenString)
+			return
+		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil // ใช้คีย์ลับที่คุณตั้งไว้
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := auth.ParseAccessToken(keys, tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token ไม่ถูกต้องหรือหมดอายุ"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if ok && token.Valid {
-			// ต้องมั่นใจว่าแปลงค่า user_id จาก token เป็น uint อย่างถูกต้อง
-			userID := uint(claims["user_id"].(float64))
-			c.Set("user_id", userID) // ✅ เก็บไว้เพื่อเรียกใช้ใน main.go
-			c.Next()
+		// Token ของ session ที่ถูก logout / ยกเลิกแล้วต้องใช้ไม่ได้ทันที แม้ยังไม่หมดอายุ
+		var family models.TokenFamily
+		if err := config.DB.Select("id", "revoked_at", "last_seen_at").
+			Where("id = ?", claims.FamilyID).Limit(1).Find(&family).Error; err != nil || family.ID == "" || family.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session นี้ถูกยกเลิกแล้ว กรุณาเข้าสู่ระบบใหม่"})
+			c.Abort()
+			return
 		}
+		touchSession(c, family)
+
+		c.Set("user_id", claims.UserID) // ✅ เก็บไว้เพื่อเรียกใช้ใน handlers
+		c.Set("role", claims.Role)
+		c.Set("family_id", claims.FamilyID)
+		c.Set("mfa", claims.MFA)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey puts the key owner into the context the same way a JWT
+// would, plus the key's id and scopes for RequirePermission and AccountScope.
+func authenticateAPIKey(c *gin.Context, plain string) {
+	now := time.Now()
+	var key models.APIKey
+	if err := config.DB.Where("key_hash = ?", auth.HashToken(plain)).Limit(1).Find(&key).Error; err != nil || key.ID == 0 || !key.Active(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key ไม่ถูกต้อง หมดอายุ หรือถูกยกเลิกแล้ว"})
+		c.Abort()
+		return
+	}
+	var user models.User
+	if err := config.DB.Select("id", "role").First(&user, key.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key ไม่ถูกต้อง หมดอายุ หรือถูกยกเลิกแล้ว"})
+		c.Abort()
+		return
+	}
+
+	// Scripts may call many times a second; one write a minute is enough.
+	if err := config.DB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", key.ID, now.Add(-touchInterval)).
+		Update("last_used_at", now).Error; err != nil {
+		log.Printf("[AUTH] api key #%d last_used_at update failed: %v", key.ID, err)
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("role", user.Role)
+	c.Set("mfa", key.MFA)
+	c.Set(apiKeyIDContextKey, key.ID)
+	c.Set(apiKeyScopesContextKey, key.Scopes)
+	c.Next()
+}
+
+// touchSession records when and from where a session was last used, at most
+// once per touchInterval.
+func touchSession(c *gin.Context, family models.TokenFamily) {
+	now := time.Now()
+	if family.LastSeenAt != nil && now.Sub(*family.LastSeenAt) < touchInterval {
+		return
+	}
+	if err := config.DB.Model(&models.TokenFamily{}).Where("id = ?", family.ID).
+		Updates(map[string]interface{}{"last_seen_at": now, "ip": c.ClientIP()}).Error; err != nil {
+		log.Printf("[AUTH] session %s last_seen_at update failed: %v", family.ID, err)
 	}
 }
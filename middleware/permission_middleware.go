@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Bannawat01/ec-space/config"
+	"github.com/Bannawat01/ec-space/models"
+	"github.com/gin-gonic/gin"
+)
+
+// roleContextKey caches the caller's role for the rest of the request.
+const roleContextKey = "role_permissions"
+
+// RequirePermission allows the request only when the caller's role grants
+// every listed permission. It must run after AuthMiddleware, which puts the
+// role and the mfa claim from the access token into the context. Roles that
+// require 2FA are refused for logins that did not pass it, and API keys also
+// need each permission among their scopes.
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := CurrentRole(c)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ตรวจสอบสิทธิ์ไม่สำเร็จ"})
+			c.Abort()
+			return
+		}
+		if !mfaSatisfied(c, role) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":        "role นี้ต้องเข้าสู่ระบบด้วย 2FA กรุณาเปิดใช้ 2FA แล้วเข้าสู่ระบบใหม่",
+				"mfa_required": true,
+			})
+			c.Abort()
+			return
+		}
+		for _, perm := range perms {
+			if !role.Can(perm) || !scopeAllows(c, perm) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":      "คุณไม่มีสิทธิ์ใช้งานส่วนนี้",
+					"permission": perm,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the caller may use perm. Handlers use it for
+// checks that depend on the request body rather than the route.
+func HasPermission(c *gin.Context, perm string) bool {
+	role, ok := CurrentRole(c)
+	return ok && mfaSatisfied(c, role) && role.Can(perm) && scopeAllows(c, perm)
+}
+
+// mfaSatisfied reports whether the login meets the role's 2FA requirement.
+func mfaSatisfied(c *gin.Context, role models.Role) bool {
+	return !role.Require2FA || c.GetBool("mfa")
+}
+
+// CurrentRole loads the caller's role and its permissions, once per request.
+func CurrentRole(c *gin.Context) (models.Role, bool) {
+	if v, ok := c.Get(roleContextKey); ok {
+		return v.(models.Role), true
+	}
+	name := c.GetString("role")
+	role, err := models.LoadRole(config.DB, name)
+	if err != nil {
+		log.Printf("[AUTH] load role %q failed: %v", name, err)
+		return models.Role{}, false
+	}
+	c.Set(roleContextKey, role)
+	return role, true
+}
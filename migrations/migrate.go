@@ -0,0 +1,252 @@
+// Package migrations applies the versioned SQL files in sql/ to the database.
+//
+// Files are named NNNN_description.up.sql / NNNN_description.down.sql and are
+// embedded into the binary. Applied versions are recorded in the
+// schema_migrations table, and a Postgres advisory lock makes sure only one
+// instance migrates at a time.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey is the pg_advisory_lock key held while migrating ("ecspace" in hex).
+const lockKey int64 = 0x65637370616365
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and whether it has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// appliedRow is a row of the schema_migrations table.
+type appliedRow struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (appliedRow) TableName() string { return "schema_migrations" }
+
+// All returns every embedded migration ordered by version.
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrations: bad file name %q", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := files.ReadFile(path.Join("sql", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrations: version %d used by %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s has no up file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func Up(db *gorm.DB) (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&appliedRow{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: %04d_%s up: %w", m.Version, m.Name, err)
+			}
+			fmt.Printf("⬆️  Applied migration %04d_%s\n", m.Version, m.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last `steps` applied migrations, newest first.
+func Down(db *gorm.DB, steps int) (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int64]Migration, len(all))
+	for _, m := range all {
+		byVersion[m.Version] = m
+	}
+
+	reverted := 0
+	err = withLock(db, func(conn *gorm.DB) error {
+		var rows []appliedRow
+		if err := conn.Order("version desc").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			m, ok := byVersion[row.Version]
+			if !ok {
+				return fmt.Errorf("migrations: applied version %d has no file", row.Version)
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migrations: %04d_%s has no down file", m.Version, m.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&appliedRow{}, "version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: %04d_%s down: %w", m.Version, m.Name, err)
+			}
+			fmt.Printf("⬇️  Reverted migration %04d_%s\n", m.Version, m.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// List reports every known migration and when it was applied.
+func List(db *gorm.DB) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, len(all))
+	for i, m := range all {
+		out[i] = Status{Version: m.Version, Name: m.Name}
+		if at, ok := done[m.Version]; ok {
+			at := at
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// Pending returns how many migrations have not been applied yet.
+func Pending(db *gorm.DB) (int, error) {
+	list, err := List(db)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range list {
+		if s.AppliedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// withLock runs fn on a single connection while holding the migration
+// advisory lock, so concurrent instances wait instead of racing.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("migrations: acquire lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`).Error
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	var rows []appliedRow
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r.AppliedAt
+	}
+	return done, nil
+}
+
+// ErrPending is returned by callers that refuse to run with an outdated schema.
+var ErrPending = errors.New("database has pending migrations; run `go run main.go migrate up`")
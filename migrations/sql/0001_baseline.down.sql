@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS credit_transactions_no_modify ON credit_transactions;
+DROP FUNCTION IF EXISTS credit_transactions_append_only();
+
+DROP TABLE IF EXISTS topup_intents;
+DROP TABLE IF EXISTS credit_transactions;
+DROP TABLE IF EXISTS order_status_histories;
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS weapons;
@@ -0,0 +1,202 @@
+-- Baseline schema: everything AutoMigrate used to create, plus the one-off
+-- fixups InitDB used to run on every boot.
+--
+-- Written to be idempotent so that databases created by the old AutoMigrate
+-- (at any point in its history) are brought to the same shape as a fresh one.
+
+-- ── weapons ─────────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS weapons (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT,
+    type        TEXT,
+    power_level BIGINT,
+    price       BIGINT,
+    description TEXT,
+    stock       BIGINT,
+    image_url   TEXT
+);
+ALTER TABLE weapons ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_weapons_archived_at ON weapons (archived_at);
+
+-- ── users ───────────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS users (
+    id       BIGSERIAL PRIMARY KEY,
+    username TEXT,
+    role     TEXT DEFAULT 'user',
+    email    TEXT NOT NULL,
+    password TEXT,
+    credits  BIGINT DEFAULT 0,
+    address  TEXT,
+    avatar   TEXT
+);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'uni_users_username') THEN
+        ALTER TABLE users ADD CONSTRAINT uni_users_username UNIQUE (username);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'uni_users_email') THEN
+        ALTER TABLE users ADD CONSTRAINT uni_users_email UNIQUE (email);
+    END IF;
+END $$;
+
+-- ── orders ──────────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS orders (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT,
+    total      BIGINT,
+    status     TEXT,
+    created_at TIMESTAMPTZ
+);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_orders_user_created ON orders (user_id, created_at DESC);
+
+-- ── order_items ─────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS order_items (
+    id        BIGSERIAL PRIMARY KEY,
+    order_id  BIGINT,
+    weapon_id BIGINT,
+    quantity  BIGINT
+);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit_price   BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS line_total   BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS weapon_name  TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS weapon_type  TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS weapon_image TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
+
+-- ── cart_items ──────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS cart_items (
+    id        BIGSERIAL PRIMARY KEY,
+    user_id   BIGINT NOT NULL,
+    weapon_id BIGINT NOT NULL,
+    quantity  BIGINT DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_cart_items_user_weapon ON cart_items (user_id, weapon_id);
+
+-- ── order_status_histories ──────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS order_status_histories (
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    BIGINT NOT NULL,
+    from_status TEXT,
+    to_status   TEXT NOT NULL,
+    changed_by  BIGINT,
+    note        TEXT,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_histories_order_id ON order_status_histories (order_id);
+
+-- ── credit_transactions ─────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS credit_transactions (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL,
+    type          TEXT NOT NULL,
+    amount        BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    order_id      BIGINT,
+    actor_id      BIGINT,
+    note          TEXT,
+    created_at    TIMESTAMPTZ
+);
+ALTER TABLE credit_transactions ADD COLUMN IF NOT EXISTS topup_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_credit_tx_user_created ON credit_transactions (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_order_id ON credit_transactions (order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_transactions_topup_id ON credit_transactions (topup_id);
+
+-- ── topup_intents ───────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS topup_intents (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL,
+    amount          BIGINT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    provider        TEXT NOT NULL,
+    provider_ref    TEXT,
+    client_secret   TEXT,
+    redirect_url    TEXT,
+    idempotency_key TEXT,
+    created_at      TIMESTAMPTZ,
+    confirmed_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_topup_intents_user_id ON topup_intents (user_id);
+CREATE INDEX IF NOT EXISTS idx_topup_intents_created_at ON topup_intents (created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_topup_provider_ref ON topup_intents (provider, provider_ref);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_topup_user_idempotency ON topup_intents (user_id, idempotency_key);
+
+-- ── Money: float credits → BIGINT minor units (× 100) ───────────────────────
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type IN ('double precision', 'numeric', 'real')
+          AND (table_name, column_name) IN (
+              ('weapons', 'price'), ('users', 'credits'),
+              ('orders', 'subtotal'), ('orders', 'discount'), ('orders', 'total'),
+              ('order_items', 'unit_price'), ('order_items', 'line_total'),
+              ('credit_transactions', 'amount'), ('credit_transactions', 'balance_after'))
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE BIGINT USING round(%I * 100)::BIGINT',
+                       col.table_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
+
+-- ── Foreign keys (same names AutoMigrate used) ──────────────────────────────
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_orders_items') THEN
+        ALTER TABLE order_items ADD CONSTRAINT fk_orders_items
+            FOREIGN KEY (order_id) REFERENCES orders (id);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_order_items_weapon') THEN
+        ALTER TABLE order_items ADD CONSTRAINT fk_order_items_weapon
+            FOREIGN KEY (weapon_id) REFERENCES weapons (id);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_cart_items_weapon') THEN
+        ALTER TABLE cart_items ADD CONSTRAINT fk_cart_items_weapon
+            FOREIGN KEY (weapon_id) REFERENCES weapons (id);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_orders_history') THEN
+        ALTER TABLE order_status_histories ADD CONSTRAINT fk_orders_history
+            FOREIGN KEY (order_id) REFERENCES orders (id);
+    END IF;
+END $$;
+
+-- ── Backfills for data created before these columns existed ────────────────
+UPDATE orders SET subtotal = total WHERE subtotal IS NULL;
+UPDATE orders SET discount = 0 WHERE discount IS NULL;
+
+UPDATE order_items oi
+SET unit_price   = w.price,
+    line_total   = w.price * oi.quantity,
+    weapon_name  = w.name,
+    weapon_type  = w.type,
+    weapon_image = w.image_url
+FROM weapons w
+WHERE w.id = oi.weapon_id AND oi.weapon_name = '';
+
+INSERT INTO order_status_histories (order_id, from_status, to_status, changed_by, note, created_at)
+SELECT o.id, '', o.status, o.user_id, '', o.created_at
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_status_histories h WHERE h.order_id = o.id);
+
+INSERT INTO credit_transactions (user_id, type, amount, balance_after, note, created_at)
+SELECT u.id, 'opening_balance', u.credits, u.credits, 'balance before ledger was introduced', NOW()
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM credit_transactions t WHERE t.user_id = u.id);
+
+-- ── credit_transactions is append-only ──────────────────────────────────────
+CREATE OR REPLACE FUNCTION credit_transactions_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'credit_transactions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS credit_transactions_no_modify ON credit_transactions;
+CREATE TRIGGER credit_transactions_no_modify
+    BEFORE UPDATE OR DELETE ON credit_transactions
+    FOR EACH ROW EXECUTE FUNCTION credit_transactions_append_only();
@@ -0,0 +1,20 @@
+CREATE TABLE token_families (
+    id             TEXT PRIMARY KEY,
+    user_id        BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at     TIMESTAMPTZ,
+    revoked_reason TEXT
+);
+CREATE INDEX idx_token_families_user_id ON token_families (user_id);
+
+CREATE TABLE refresh_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    family_id  TEXT NOT NULL REFERENCES token_families (id) ON DELETE CASCADE,
+    user_id    BIGINT NOT NULL,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
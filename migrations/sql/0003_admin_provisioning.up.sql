@@ -0,0 +1,27 @@
+-- Admins used to be created by registering the magic username "admin_boss".
+-- That rule is gone; admins now come from `go run main.go admin ...` or from
+-- an existing admin via PATCH /api/admin/users/:id/role, and every change is
+-- written to audit_logs.
+
+CREATE TABLE audit_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    actor_id    BIGINT,
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id   BIGINT NOT NULL,
+    details     TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_target ON audit_logs (target_type, target_id);
+
+-- Existing admins keep their role. Record that they were granted it by the
+-- old rule so the audit trail starts complete.
+INSERT INTO audit_logs (actor_id, action, target_type, target_id, details, created_at)
+SELECT NULL, 'user.role.grandfathered', 'user', id,
+       '{"role":"admin","source":"pre-provisioning admin account"}', NOW()
+FROM users
+WHERE role = 'admin';
+
+UPDATE users SET role = 'user' WHERE role IS NULL OR role = '';
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_role;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+
+-- Only "user" and "admin" exist without the roles table, so staff roles
+-- other than superuser fall back to customer accounts.
+UPDATE users SET role = 'admin' WHERE role = 'superuser';
+UPDATE users SET role = 'user' WHERE role <> 'admin';
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
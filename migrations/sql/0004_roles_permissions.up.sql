@@ -0,0 +1,60 @@
+-- users.role used to be the free-form string "user" or "admin". It now names
+-- a row in roles, and each role grants a set of permissions that routes
+-- check with middleware.RequirePermission. Existing admins become superusers.
+
+CREATE TABLE roles (
+    name         TEXT PRIMARY KEY,
+    description  TEXT NOT NULL DEFAULT '',
+    is_superuser BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE permissions (
+    code        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role_name       TEXT NOT NULL REFERENCES roles (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission_code TEXT NOT NULL REFERENCES permissions (code) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission_code)
+);
+
+INSERT INTO permissions (code, description) VALUES
+    ('catalog:write',   'Add and edit weapons'),
+    ('catalog:archive', 'Archive, restore and list archived weapons'),
+    ('orders:read',     'View every customer''s orders'),
+    ('orders:update',   'Move orders through fulfilment'),
+    ('orders:refund',   'Cancel or refund orders'),
+    ('users:roles',     'View roles and change user roles');
+
+INSERT INTO roles (name, description, is_superuser) VALUES
+    ('user',            'Customer account', FALSE),
+    ('superuser',       'Full access to every admin feature', TRUE),
+    ('catalog_manager', 'Maintains the weapon catalog', FALSE),
+    ('order_fulfiller', 'Ships and delivers orders', FALSE),
+    ('finance',         'Handles cancellations and refunds', FALSE),
+    ('support',         'Looks up orders to help customers', FALSE);
+
+INSERT INTO role_permissions (role_name, permission_code) VALUES
+    ('catalog_manager', 'catalog:write'),
+    ('catalog_manager', 'catalog:archive'),
+    ('order_fulfiller', 'orders:read'),
+    ('order_fulfiller', 'orders:update'),
+    ('finance',         'orders:read'),
+    ('finance',         'orders:update'),
+    ('finance',         'orders:refund'),
+    ('support',         'orders:read');
+
+INSERT INTO audit_logs (actor_id, action, target_type, target_id, details, created_at)
+SELECT NULL, 'user.role.changed', 'user', id,
+       '{"from":"admin","to":"superuser","reason":"admin role replaced by permission model"}', NOW()
+FROM users
+WHERE role = 'admin';
+
+UPDATE users SET role = 'superuser' WHERE role = 'admin';
+UPDATE users SET role = 'user' WHERE role IS NULL OR role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles (name) ON UPDATE CASCADE;
+CREATE INDEX idx_users_role ON users (role);
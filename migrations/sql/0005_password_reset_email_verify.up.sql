@@ -0,0 +1,19 @@
+-- Password reset and email verification. Both send a single-use link by
+-- email; only the SHA-256 hash of the token is stored in user_tokens.
+-- Existing accounts start unverified and can ask for a new link.
+
+ALTER TABLE users ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ;
+
+CREATE TABLE user_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verify')),
+    email      TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_user_tokens_token_hash ON user_tokens (token_hash);
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id, purpose);
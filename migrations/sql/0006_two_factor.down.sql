@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE token_families DROP COLUMN IF EXISTS mfa;
+ALTER TABLE roles DROP COLUMN IF EXISTS require_2fa;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
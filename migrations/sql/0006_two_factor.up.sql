@@ -0,0 +1,32 @@
+-- Optional TOTP two-factor authentication. Roles can require it, in which
+-- case their permissions only apply to logins that passed the second factor
+-- (token_families.mfa, carried into access tokens as the "mfa" claim).
+
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE roles ADD COLUMN require_2fa BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE token_families ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE login_challenges (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_login_challenges_token_hash ON login_challenges (token_hash);
+CREATE INDEX idx_login_challenges_user_id ON login_challenges (user_id);
@@ -0,0 +1,19 @@
+-- Failed-login tracking per username and per client IP, used for
+-- exponential backoff and temporary lockout. Staff with users:unlock can
+-- clear a username's lockout.
+
+CREATE TABLE login_throttles (
+    scope           TEXT NOT NULL CHECK (scope IN ('username', 'ip')),
+    key             TEXT NOT NULL,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+CREATE INDEX idx_login_throttles_last_failure_at ON login_throttles (last_failure_at);
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:unlock', 'Clear a login lockout');
+
+INSERT INTO role_permissions (role_name, permission_code) VALUES
+    ('support', 'users:unlock');
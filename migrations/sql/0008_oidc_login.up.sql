@@ -0,0 +1,26 @@
+-- OpenID Connect login. user_identities links a local user to an account at
+-- an external provider; oidc_states holds logins that were sent to the
+-- provider and have not come back yet.
+
+CREATE TABLE user_identities (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE oidc_states (
+    id            BIGSERIAL PRIMARY KEY,
+    state_hash    TEXT NOT NULL,
+    provider      TEXT NOT NULL,
+    nonce         TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_oidc_states_state_hash ON oidc_states (state_hash);
+CREATE INDEX idx_oidc_states_expires_at ON oidc_states (expires_at);
@@ -0,0 +1,14 @@
+-- Asymmetric (RS256 / EdDSA) access token signing keys. The id is the kid
+-- header of the tokens a key signs. private_key is PKCS#8, encrypted with a
+-- key derived from auth.jwt_secret. A key signs from activates_at until a
+-- newer key takes over, and is accepted until expires_at (NULL = current).
+
+CREATE TABLE signing_keys (
+    id           TEXT PRIMARY KEY,
+    algorithm    TEXT NOT NULL CHECK (algorithm IN ('RS256', 'EdDSA')),
+    private_key  TEXT NOT NULL,
+    activates_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_signing_keys_expires_at ON signing_keys (expires_at);
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'users:sessions';
+
+ALTER TABLE token_families
+    DROP COLUMN last_seen_at,
+    DROP COLUMN user_agent,
+    DROP COLUMN ip,
+    DROP COLUMN device;
@@ -0,0 +1,17 @@
+-- Token families double as the sessions users see in GET /api/sessions:
+-- where each login came from and when it was last used. Staff with
+-- users:sessions can list a user's sessions and log them out everywhere.
+
+ALTER TABLE token_families
+    ADD COLUMN device       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip           TEXT NOT NULL DEFAULT '',
+    ADD COLUMN user_agent   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMPTZ;
+
+UPDATE token_families SET last_seen_at = created_at;
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:sessions', 'See a user''s sessions and log them out everywhere');
+
+INSERT INTO role_permissions (role_name, permission_code) VALUES
+    ('support', 'users:sessions');
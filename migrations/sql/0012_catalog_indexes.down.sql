@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_weapons_in_stock;
+DROP INDEX IF EXISTS idx_weapons_name_lower;
+DROP INDEX IF EXISTS idx_weapons_power_level;
+DROP INDEX IF EXISTS idx_weapons_price;
+DROP INDEX IF EXISTS idx_weapons_type_price;
@@ -0,0 +1,9 @@
+-- Indexes behind catalog search on GET /api/weapons: filtering by type, price
+-- and power, the in-stock filter and every sort order. Only live (not
+-- archived) weapons are ever listed, so the indexes skip archived rows.
+
+CREATE INDEX idx_weapons_type_price ON weapons (type, price) WHERE archived_at IS NULL;
+CREATE INDEX idx_weapons_price ON weapons (price, id) WHERE archived_at IS NULL;
+CREATE INDEX idx_weapons_power_level ON weapons (power_level, id) WHERE archived_at IS NULL;
+CREATE INDEX idx_weapons_name_lower ON weapons (lower(name), id) WHERE archived_at IS NULL;
+CREATE INDEX idx_weapons_in_stock ON weapons (id) WHERE archived_at IS NULL AND stock > 0;
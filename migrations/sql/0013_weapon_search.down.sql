@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_weapons_description_trgm;
+DROP INDEX IF EXISTS idx_weapons_name_trgm;
+DROP INDEX IF EXISTS idx_weapons_search_vector;
+
+ALTER TABLE weapons DROP COLUMN IF EXISTS search_vector;
+
+-- pg_trgm is left installed; other objects may depend on it.
@@ -0,0 +1,23 @@
+-- Full-text search over the catalog. search_vector is kept up to date by
+-- Postgres itself: name weighs most, then type, then description. Each text
+-- is indexed with the english config (stemming, so "rifles" finds "rifle")
+-- and the simple config, which keeps Thai and other words as written.
+--
+-- Thai has no spaces between words, so the full-text index only finds whole
+-- phrases; the trigram indexes back up substring (ILIKE) matches and the
+-- typo-tolerant autocomplete on GET /api/weapons/suggest.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE weapons ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(type, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(type, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'C') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_weapons_search_vector ON weapons USING GIN (search_vector);
+CREATE INDEX idx_weapons_name_trgm ON weapons USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_weapons_description_trgm ON weapons USING GIN (description gin_trgm_ops);
@@ -0,0 +1,8 @@
+UPDATE permissions SET description = 'Add and edit weapons' WHERE code = 'catalog:write';
+
+ALTER TABLE weapons DROP CONSTRAINT IF EXISTS fk_weapons_category;
+ALTER TABLE weapons ALTER COLUMN type DROP NOT NULL;
+
+UPDATE weapons w SET type = c.names ->> 'en' FROM categories c WHERE c.slug = w.type;
+
+DROP TABLE IF EXISTS categories;
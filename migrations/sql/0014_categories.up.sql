@@ -0,0 +1,55 @@
+-- weapons.type used to be free text, with the valid values kept only in the
+-- frontend. It now holds the slug of a row in categories. Categories have a
+-- display name per language (names must include "en"), can nest through
+-- parent_id, are ordered by sort_order and may carry an icon.
+--
+-- Existing weapons are matched to the seeded categories by their English
+-- name; any other type becomes a category of its own so nothing is lost.
+
+CREATE TABLE categories (
+    id         BIGSERIAL PRIMARY KEY,
+    slug       TEXT NOT NULL,
+    names      JSONB NOT NULL,
+    parent_id  BIGINT REFERENCES categories (id),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    icon       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_categories_name_en CHECK (coalesce(names ->> 'en', '') <> '')
+);
+CREATE UNIQUE INDEX idx_categories_slug ON categories (slug);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+INSERT INTO categories (slug, names, sort_order, icon) VALUES
+    ('plasma',          '{"en": "Plasma", "th": "พลาสมา"}',                      10, 'atom'),
+    ('kinetic-railgun', '{"en": "Kinetic / Railgun", "th": "คิเนติก / เรลกัน"}', 20, 'magnet'),
+    ('laser',           '{"en": "Laser", "th": "เลเซอร์"}',                      30, 'zap'),
+    ('sonic',           '{"en": "Sonic", "th": "คลื่นเสียง"}',                   40, 'audio-waveform'),
+    ('sniper',          '{"en": "Sniper", "th": "สไนเปอร์"}',                    50, 'crosshair'),
+    ('melee',           '{"en": "Melee", "th": "อาวุธระยะประชิด"}',              60, 'sword'),
+    ('standard',        '{"en": "Standard", "th": "มาตรฐาน"}',                   70, 'shield');
+
+INSERT INTO categories (slug, names, sort_order)
+SELECT DISTINCT ON (slug) slug, jsonb_build_object('en', type), 100
+FROM (
+    SELECT type,
+           coalesce(nullif(trim(both '-' FROM regexp_replace(lower(type), '[^a-z0-9]+', '-', 'g')), ''),
+                    'category-' || substr(md5(type), 1, 8)) AS slug
+    FROM weapons
+    WHERE coalesce(type, '') <> ''
+) t
+WHERE NOT EXISTS (SELECT 1 FROM categories c WHERE c.slug = t.slug OR c.names ->> 'en' = t.type)
+ORDER BY slug, type;
+
+UPDATE weapons w SET type = c.slug FROM categories c WHERE c.names ->> 'en' = w.type;
+UPDATE weapons
+SET type = coalesce(nullif(trim(both '-' FROM regexp_replace(lower(type), '[^a-z0-9]+', '-', 'g')), ''),
+                    'category-' || substr(md5(type), 1, 8))
+WHERE coalesce(type, '') <> '' AND type NOT IN (SELECT slug FROM categories);
+UPDATE weapons SET type = 'standard' WHERE coalesce(type, '') = '';
+
+ALTER TABLE weapons ALTER COLUMN type SET NOT NULL;
+ALTER TABLE weapons
+    ADD CONSTRAINT fk_weapons_category FOREIGN KEY (type) REFERENCES categories (slug) ON UPDATE CASCADE;
+
+UPDATE permissions SET description = 'Add and edit weapons and categories' WHERE code = 'catalog:write';
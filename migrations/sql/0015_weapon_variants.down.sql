@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS weapon_variants_stock ON weapon_variants;
+DROP FUNCTION IF EXISTS weapon_variants_sync_stock();
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS variant_options,
+    DROP COLUMN IF EXISTS sku,
+    DROP COLUMN IF EXISTS variant_id;
+
+-- A cart can now hold several variants of one weapon; keep one line each.
+DELETE FROM cart_items a USING cart_items b
+WHERE a.user_id = b.user_id AND a.weapon_id = b.weapon_id AND a.id > b.id;
+DROP INDEX IF EXISTS idx_cart_items_user_variant;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS weapon_variants;
@@ -0,0 +1,61 @@
+-- Weapons are sold as variants (SKUs): colorway, charge capacity, scope and
+-- so on live in options, each variant has its own stock and may override the
+-- weapon's price. Every existing weapon gets one default variant holding its
+-- stock, and carts and past orders are pointed at it.
+--
+-- weapons.stock stays as the total of a weapon's live variants, kept up to
+-- date by the trigger below, so catalog filters and listings keep working.
+
+CREATE TABLE weapon_variants (
+    id          BIGSERIAL PRIMARY KEY,
+    weapon_id   BIGINT NOT NULL REFERENCES weapons (id),
+    sku         TEXT NOT NULL,
+    options     JSONB NOT NULL DEFAULT '{}',
+    price       BIGINT CHECK (price IS NULL OR price >= 0),
+    stock       BIGINT NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    archived_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_weapon_variants_sku ON weapon_variants (sku);
+CREATE INDEX idx_weapon_variants_weapon_id ON weapon_variants (weapon_id);
+CREATE INDEX idx_weapon_variants_archived_at ON weapon_variants (archived_at);
+-- No two live variants of a weapon may have the same options.
+CREATE UNIQUE INDEX idx_weapon_variants_options ON weapon_variants (weapon_id, options) WHERE archived_at IS NULL;
+
+INSERT INTO weapon_variants (weapon_id, sku, stock)
+SELECT id, 'W' || id, greatest(coalesce(stock, 0), 0) FROM weapons;
+
+ALTER TABLE cart_items ADD COLUMN variant_id BIGINT REFERENCES weapon_variants (id);
+UPDATE cart_items ci SET variant_id = v.id FROM weapon_variants v WHERE v.weapon_id = ci.weapon_id;
+ALTER TABLE cart_items ALTER COLUMN variant_id SET NOT NULL;
+CREATE INDEX idx_cart_items_user_variant ON cart_items (user_id, variant_id);
+
+ALTER TABLE order_items
+    ADD COLUMN variant_id      BIGINT REFERENCES weapon_variants (id),
+    ADD COLUMN sku             TEXT NOT NULL DEFAULT '',
+    ADD COLUMN variant_options JSONB NOT NULL DEFAULT '{}';
+UPDATE order_items oi SET variant_id = v.id, sku = v.sku FROM weapon_variants v WHERE v.weapon_id = oi.weapon_id;
+ALTER TABLE order_items ALTER COLUMN variant_id SET NOT NULL;
+
+CREATE OR REPLACE FUNCTION weapon_variants_sync_stock() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE weapons SET stock = (
+            SELECT coalesce(sum(v.stock), 0) FROM weapon_variants v
+            WHERE v.weapon_id = OLD.weapon_id AND v.archived_at IS NULL
+        ) WHERE id = OLD.weapon_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE weapons SET stock = (
+            SELECT coalesce(sum(v.stock), 0) FROM weapon_variants v
+            WHERE v.weapon_id = NEW.weapon_id AND v.archived_at IS NULL
+        ) WHERE id = NEW.weapon_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER weapon_variants_stock
+    AFTER INSERT OR UPDATE OR DELETE ON weapon_variants
+    FOR EACH ROW EXECUTE FUNCTION weapon_variants_sync_stock();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_weapons_specs;
+ALTER TABLE weapons DROP COLUMN IF EXISTS specs;
+
+DROP TABLE IF EXISTS spec_attributes;
@@ -0,0 +1,46 @@
+-- Technical specifications. Each category has a schema of typed attributes
+-- (spec_attributes) that its weapons and the weapons of its subcategories
+-- fill in; the values live in weapons.specs as a JSON object keyed by the
+-- attribute key.
+--
+-- Top-level categories get a starter schema. Nothing is required, so
+-- existing weapons stay valid.
+
+CREATE TABLE spec_attributes (
+    id          BIGSERIAL PRIMARY KEY,
+    category_id BIGINT NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    key         TEXT NOT NULL,
+    names       JSONB NOT NULL,
+    type        TEXT NOT NULL CHECK (type IN ('number', 'integer', 'text', 'enum', 'boolean')),
+    unit        TEXT NOT NULL DEFAULT '',
+    options     JSONB NOT NULL DEFAULT '[]',
+    min         DOUBLE PRECISION,
+    max         DOUBLE PRECISION,
+    required    BOOLEAN NOT NULL DEFAULT FALSE,
+    filterable  BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order  INTEGER NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_spec_attributes_name_en CHECK (coalesce(names ->> 'en', '') <> ''),
+    CONSTRAINT chk_spec_attributes_range CHECK (min IS NULL OR max IS NULL OR min <= max)
+);
+CREATE UNIQUE INDEX idx_spec_attributes_category_key ON spec_attributes (category_id, key);
+
+ALTER TABLE weapons ADD COLUMN specs JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX idx_weapons_specs ON weapons USING GIN (specs jsonb_path_ops);
+
+INSERT INTO spec_attributes (category_id, key, names, type, unit, options, min, filterable, sort_order)
+SELECT c.id, a.key, a.names::jsonb, a.type, a.unit, a.options::jsonb, a.min, a.filterable, a.sort_order
+FROM categories c
+CROSS JOIN (VALUES
+    ('range_m',       '{"en": "Effective range", "th": "ระยะหวังผล"}',   'number',  'm',   '[]', 0, TRUE,  10),
+    ('fire_rate',     '{"en": "Fire rate", "th": "อัตรายิง"}',           'integer', 'rpm', '[]', 0, TRUE,  20),
+    ('energy_type',   '{"en": "Energy type", "th": "ประเภทพลังงาน"}',   'enum',    '',
+        '["plasma", "kinetic", "laser", "sonic", "chemical"]', NULL, TRUE, 30),
+    ('ammo_capacity', '{"en": "Ammo capacity", "th": "ความจุกระสุน"}',  'integer', '',    '[]', 0, TRUE,  40)
+) AS a (key, names, type, unit, options, min, filterable, sort_order)
+WHERE c.parent_id IS NULL AND c.slug <> 'melee';
+
+INSERT INTO spec_attributes (category_id, key, names, type, unit, min, filterable, sort_order)
+SELECT id, 'weight_kg', '{"en": "Weight", "th": "น้ำหนัก"}', 'number', 'kg', 0, TRUE, 50
+FROM categories WHERE parent_id IS NULL;
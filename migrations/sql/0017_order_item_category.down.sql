@@ -0,0 +1,2 @@
+UPDATE order_items SET weapon_type = weapon_category_name WHERE weapon_category_name <> '';
+ALTER TABLE order_items DROP COLUMN IF EXISTS weapon_category_name;
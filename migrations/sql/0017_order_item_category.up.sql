@@ -0,0 +1,25 @@
+-- order_items.weapon_type holds the weapon's category slug at checkout, the
+-- same value as weapons.type. The category's display name gets its own
+-- column. Lines written so far stored the display name (or, before
+-- categories existed, the free-text type) in weapon_type; move it over and
+-- turn weapon_type back into a slug the way 0014 did for weapons.
+
+ALTER TABLE order_items ADD COLUMN weapon_category_name TEXT NOT NULL DEFAULT '';
+
+UPDATE order_items SET weapon_category_name = weapon_type WHERE weapon_type <> '';
+
+UPDATE order_items oi SET weapon_type = c.slug
+FROM categories c
+WHERE c.names ->> 'en' = oi.weapon_type;
+UPDATE order_items
+SET weapon_type = coalesce(nullif(trim(both '-' FROM regexp_replace(lower(weapon_type), '[^a-z0-9]+', '-', 'g')), ''),
+                           'category-' || substr(md5(weapon_type), 1, 8))
+WHERE weapon_type <> '' AND weapon_type NOT IN (SELECT slug FROM categories);
+
+-- Lines whose category lookup missed at checkout fall back to the weapon.
+UPDATE order_items oi
+SET weapon_type          = w.type,
+    weapon_category_name = coalesce(c.names ->> 'en', '')
+FROM weapons w
+LEFT JOIN categories c ON c.slug = w.type
+WHERE w.id = oi.weapon_id AND oi.weapon_type = '';
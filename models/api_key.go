@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scopes for the customer side of the API. A key also needs one of these to
+// use /api routes that aren't under /api/admin; staff routes are unlocked by
+// adding the permission code itself (e.g. "orders:read") as a scope.
+const (
+	ScopeAccountRead  = "account:read"  // GET profile, cart, orders, wallet
+	ScopeAccountWrite = "account:write" // change profile, cart, place and cancel orders, top up
+)
+
+// APIKey is a long-lived credential for scripts. Only the SHA-256 hash of the
+// key is stored; Prefix is kept so users can tell their keys apart.
+//
+// A key acts as its owner with at most its scopes. The owner's role is read
+// on every request, so a key never keeps permissions the owner has lost.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     ScopeList  `json:"scopes" gorm:"type:text;not null"`
+	MFA        bool       `json:"-" gorm:"column:mfa;not null;default:false"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string { return "api_keys" }
+
+// Active reports whether the key can still authenticate at t.
+func (k APIKey) Active(t time.Time) bool {
+	return k.RevokedAt == nil && t.Before(k.ExpiresAt)
+}
+
+// ScopeList is stored as a space-separated string, like an OAuth scope.
+type ScopeList []string
+
+// Has reports whether scope is in the list.
+func (s ScopeList) Has(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ScopeList) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+func (s *ScopeList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*s = strings.Fields(v)
+	case []byte:
+		*s = strings.Fields(string(v))
+	case nil:
+		*s = nil
+	default:
+		return fmt.Errorf("models: cannot scan %T into ScopeList", src)
+	}
+	return nil
+}
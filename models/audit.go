@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AuditLog records a privileged action: who did what to which record.
+// ActorID is nil for actions run from the command line.
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actor_id,omitempty" gorm:"index"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type" gorm:"not null"`
+	TargetID   uint      `json:"target_id" gorm:"not null"`
+	Details    string    `json:"details" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -1,9 +1,13 @@
 package models
 
+// CartItem is a quantity of one variant. WeaponID is kept alongside
+// VariantID so the cart can load both in one go.
 type CartItem struct {
-	ID       uint   `gorm:"primaryKey" json:"id"`
-	UserID   uint   `gorm:"not null" json:"user_id"`
-	WeaponID uint   `gorm:"not null" json:"weapon_id"`
-	Quantity int    `gorm:"default:1" json:"quantity"`
-	Weapon   Weapon `gorm:"foreignKey:WeaponID" json:"weapon"`
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	UserID    uint          `gorm:"not null" json:"user_id"`
+	WeaponID  uint          `gorm:"not null" json:"weapon_id"`
+	VariantID uint          `gorm:"not null" json:"variant_id"`
+	Quantity  int           `gorm:"default:1" json:"quantity"`
+	Weapon    Weapon        `gorm:"foreignKey:WeaponID" json:"weapon"`
+	Variant   WeaponVariant `gorm:"foreignKey:VariantID" json:"variant"`
 }
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultLanguage is the language every category must have a name in, and
+// the fallback when a name is missing in the requested language.
+const DefaultLanguage = "en"
+
+// Category groups weapons in the catalog. Weapons reference it by Slug in
+// weapons.type, so renaming a slug carries over to its weapons. Categories
+// nest through ParentID and are listed by SortOrder within each level.
+type Category struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	Slug      string        `json:"slug" gorm:"not null;uniqueIndex"`
+	Names     LocalizedText `json:"names" gorm:"type:jsonb;not null"`
+	ParentID  *uint         `json:"parent_id" gorm:"index"`
+	SortOrder int           `json:"sort_order" gorm:"not null;default:0"`
+	// Icon is a lucide icon name (e.g. "crosshair") or an image URL.
+	Icon      string    `json:"icon" gorm:"not null;default:''"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LocalizedText maps a language code ("en", "th") to text. It is stored as
+// a JSONB object.
+type LocalizedText map[string]string
+
+// Get returns the text in lang, falling back to DefaultLanguage.
+func (t LocalizedText) Get(lang string) string {
+	if v := t[lang]; v != "" {
+		return v
+	}
+	return t[DefaultLanguage]
+}
+
+func (t LocalizedText) Value() (driver.Value, error) {
+	return jsonMapValue(t)
+}
+
+func (t *LocalizedText) Scan(src interface{}) error {
+	return scanJSON(src, (*map[string]string)(t), "LocalizedText")
+}
+
+// jsonMapValue stores a string map as a JSON object; nil becomes {}.
+func jsonMapValue(m map[string]string) (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// scanJSON reads a JSON column into dst; NULL leaves maps and slices nil.
+func scanJSON(src interface{}, dst interface{}, typeName string) error {
+	var raw []byte
+	switch v := src.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case nil:
+		raw = []byte("null")
+	default:
+		return fmt.Errorf("models: cannot scan %T into %s", src, typeName)
+	}
+	return json.Unmarshal(raw, dst)
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Credit transaction types.
+const (
+	CreditTxSignupBonus    = "signup_bonus"
+	CreditTxTopup          = "topup"
+	CreditTxPurchase       = "purchase"
+	CreditTxRefund         = "refund"
+	CreditTxOpeningBalance = "opening_balance"
+)
+
+// CreditTransaction is one append-only entry in a user's credit ledger.
+// Amount is signed: positive for money in, negative for money out.
+type CreditTransaction struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"index:idx_credit_tx_user_created,priority:1;not null"`
+	Type         string    `json:"type" gorm:"not null"`
+	Amount       Money     `json:"amount" gorm:"not null"`
+	BalanceAfter Money     `json:"balance_after" gorm:"not null"`
+	OrderID      *uint     `json:"order_id,omitempty" gorm:"index"`
+	TopupID      *uint     `json:"topup_id,omitempty" gorm:"uniqueIndex"`
+	ActorID      *uint     `json:"actor_id,omitempty"`
+	Note         string    `json:"note,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index:idx_credit_tx_user_created,priority:2"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect
+// provider. Subject is the provider's stable "sub" claim.
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string    `json:"-" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCState is a pending OpenID Connect login: the state parameter sent to
+// the provider (hashed), plus the nonce and PKCE verifier needed to finish
+// it. Each one can be used once.
+type OIDCState struct {
+	ID           uint      `gorm:"primaryKey"`
+	StateHash    string    `gorm:"not null;uniqueIndex"`
+	Provider     string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (OIDCState) TableName() string { return "oidc_states" }
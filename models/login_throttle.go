@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Scopes of a LoginThrottle.
+const (
+	ThrottleScopeUsername = "username"
+	ThrottleScopeIP       = "ip"
+)
+
+// LoginThrottle counts recent failed logins for one username or one client
+// IP. While LockedUntil is in the future, logins for that key are refused
+// without checking the password.
+type LoginThrottle struct {
+	Scope         string     `json:"scope" gorm:"primaryKey"`
+	Key           string     `json:"key" gorm:"primaryKey"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
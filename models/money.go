@@ -0,0 +1,102 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MinorUnits is the number of Money units in one credit (two decimal places).
+const MinorUnits = 100
+
+// Money is an amount of credits stored as integer minor units (1 CR = 100).
+// It is stored as BIGINT and marshals to JSON as a plain decimal number, the
+// same way the old float64 fields did (10000, 1234.5, 0.05).
+type Money int64
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+// Credits converts a whole number of credits to Money.
+func Credits(n int64) Money {
+	return Money(n * MinorUnits)
+}
+
+// ParseMoney parses a decimal string such as "1234.50" into Money.
+// Values with more than two decimals are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errInvalidMoney
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, s)
+	}
+	r.Mul(r, big.NewRat(MinorUnits, 1))
+
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Round half away from zero: |2*rem| >= den.
+	if new(big.Int).Abs(new(big.Int).Mul(rem, big.NewInt(2))).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %q out of range", errInvalidMoney, s)
+	}
+	return Money(q.Int64()), nil
+}
+
+// Mul returns m multiplied by a quantity.
+func (m Money) Mul(qty int) Money {
+	return m * Money(qty)
+}
+
+// String formats m as a decimal number without trailing zeros, e.g. "1234.5".
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/MinorUnits, v%MinorUnits
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	return strings.TrimRight(fmt.Sprintf("%s%d.%02d", sign, whole, frac), "0")
+}
+
+// MarshalJSON writes m as a JSON number in credits.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string in credits.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	v, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
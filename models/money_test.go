@@ -0,0 +1,128 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "10", want: 1000},
+		{in: "1234.50", want: 123450},
+		{in: " 1234.5 ", want: 123450},
+		{in: "0.05", want: 5},
+		{in: "-12.34", want: -1234},
+
+		// Half away from zero
+		{in: "0.005", want: 1},
+		{in: "-0.005", want: -1},
+		{in: "0.015", want: 2},
+		{in: "-0.015", want: -2},
+		{in: "0.0049", want: 0},
+		{in: "-0.0049", want: 0},
+
+		// More than two decimal places
+		{in: "1.234", want: 123},
+		{in: "1.235", want: 124},
+		{in: "1.2349999", want: 123},
+		{in: "99.999", want: 10000},
+
+		// Bounds of int64 minor units
+		{in: "92233720368547758.07", want: 1<<63 - 1},
+		{in: "-92233720368547758.08", want: -1 << 63},
+		{in: "92233720368547758.08", wantErr: true},
+		{in: "-92233720368547758.09", wantErr: true},
+		{in: "1e30", wantErr: true},
+
+		// Not a number
+		{in: "", wantErr: true},
+		{in: "   ", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "12,50", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, errInvalidMoney) {
+				t.Errorf("ParseMoney(%q) = %d, %v; want errInvalidMoney", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		m    Money
+		json string
+	}{
+		{0, "0"},
+		{5, "0.05"},
+		{150, "1.5"},
+		{1000000, "10000"},
+		{123456, "1234.56"},
+		{-1234, "-12.34"},
+		{-50, "-0.5"},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.m)
+		if err != nil || string(b) != tt.json {
+			t.Errorf("Marshal(%d) = %s, %v; want %s", tt.m, b, err, tt.json)
+			continue
+		}
+		var back Money
+		if err := json.Unmarshal(b, &back); err != nil || back != tt.m {
+			t.Errorf("round trip of %d = %d, %v", tt.m, back, err)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `12.5`, want: 1250},
+		{in: `"12.5"`, want: 1250},
+		{in: `0.005`, want: 1},
+		{in: `null`, want: 777}, // leaves the value untouched
+		{in: `""`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `1e30`, wantErr: true},
+	}
+	for _, tt := range tests {
+		m := Money(777)
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, m, tt.want)
+		}
+	}
+
+	var body struct {
+		Price *Money `json:"price"`
+		Total Money  `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(`{"price":"1999.99","total":3999.98}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Price == nil || *body.Price != 199999 || body.Total != 399998 {
+		t.Errorf("struct decode = %v/%d, want 199999/399998", body.Price, body.Total)
+	}
+}
@@ -3,18 +3,53 @@ package models
 import "time"
 
 type Order struct {
-	ID        uint        `json:"id" gorm:"primaryKey"`
-	UserID    uint        `json:"user_id"`
-	Total     float64     `json:"total"`
-	Status    string      `json:"status"`
-	CreatedAt time.Time   `json:"created_at"`
-	Items     []OrderItem `json:"items"`
+	ID        uint                 `json:"id" gorm:"primaryKey"`
+	UserID    uint                 `json:"user_id"`
+	Subtotal  Money                `json:"subtotal"`
+	Discount  Money                `json:"discount"`
+	Total     Money                `json:"total"`
+	Status    string               `json:"status"`
+	CreatedAt time.Time            `json:"created_at"`
+	Items     []OrderItem          `json:"items"`
+	History   []OrderStatusHistory `json:"history"`
+
+	CancelReason string     `json:"cancel_reason,omitempty" gorm:"type:text"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
 }
 
+// OrderItem keeps a snapshot of the weapon and variant taken at checkout so
+// that later catalog edits never change what an old order shows. WeaponType
+// is the category slug, like Weapon.Type, and WeaponCategoryName is that
+// category's name in DefaultLanguage.
 type OrderItem struct {
-	ID       uint   `json:"id" gorm:"primaryKey"`
-	OrderID  uint   `json:"order_id"`
-	WeaponID uint   `json:"weapon_id"`
-	Quantity int    `json:"quantity"`
-	Weapon   Weapon `gorm:"foreignKey:WeaponID" json:"weapon"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	OrderID            uint           `json:"order_id"`
+	WeaponID           uint           `json:"weapon_id"`
+	VariantID          uint           `json:"variant_id" gorm:"not null"`
+	Quantity           int            `json:"quantity"`
+	UnitPrice          Money          `json:"unit_price" gorm:"not null;default:0"`
+	LineTotal          Money          `json:"line_total" gorm:"not null;default:0"`
+	WeaponName         string         `json:"weapon_name" gorm:"not null;default:''"`
+	WeaponType         string         `json:"weapon_type" gorm:"not null;default:''"`
+	WeaponCategoryName string         `json:"weapon_category_name" gorm:"not null;default:''"`
+	WeaponImage        string         `json:"weapon_image" gorm:"not null;default:''"`
+	SKU                string         `json:"sku" gorm:"column:sku;not null;default:''"`
+	VariantOptions     VariantOptions `json:"variant_options" gorm:"type:jsonb;not null;default:'{}'"`
+	Weapon             Weapon         `gorm:"foreignKey:WeaponID" json:"weapon"`
+}
+
+// SnapshotWeapon returns the weapon as it was at checkout, built from the
+// snapshot columns instead of the live weapons row.
+func (i OrderItem) SnapshotWeapon() Weapon {
+	w := Weapon{
+		ID:       i.WeaponID,
+		Name:     i.WeaponName,
+		Type:     i.WeaponType,
+		Price:    i.UnitPrice,
+		ImageURL: i.WeaponImage,
+	}
+	if i.WeaponCategoryName != "" {
+		w.Category = &Category{Slug: i.WeaponType, Names: LocalizedText{DefaultLanguage: i.WeaponCategoryName}}
+	}
+	return w
 }
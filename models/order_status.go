@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Order lifecycle statuses.
+const (
+	OrderStatusPaid       = "paid"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusRefunded   = "refunded"
+)
+
+// orderTransitions lists the statuses each status may move to.
+// Cancelled and refunded are terminal.
+var orderTransitions = map[string][]string{
+	OrderStatusPaid:       {OrderStatusProcessing, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusRefunded},
+	OrderStatusDelivered:  {OrderStatusRefunded},
+}
+
+// IsValidOrderStatus reports whether s is a known lifecycle status.
+func IsValidOrderStatus(s string) bool {
+	switch s {
+	case OrderStatusPaid, OrderStatusProcessing, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another.
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusHistory is one entry in an order's status timeline.
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    uint      `json:"order_id" gorm:"index;not null"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	ChangedBy  uint      `json:"changed_by"`
+	Note       string    `json:"note" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
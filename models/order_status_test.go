@@ -0,0 +1,84 @@
+package models
+
+import "testing"
+
+func TestCanTransitionOrder(t *testing.T) {
+	statuses := []string{
+		OrderStatusPaid, OrderStatusProcessing, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded,
+	}
+	// Every from→to pair of known statuses; cancelled and refunded are terminal.
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderStatusPaid, OrderStatusPaid, false},
+		{OrderStatusPaid, OrderStatusProcessing, true},
+		{OrderStatusPaid, OrderStatusShipped, false},
+		{OrderStatusPaid, OrderStatusDelivered, false},
+		{OrderStatusPaid, OrderStatusCancelled, true},
+		{OrderStatusPaid, OrderStatusRefunded, true},
+
+		{OrderStatusProcessing, OrderStatusPaid, false},
+		{OrderStatusProcessing, OrderStatusProcessing, false},
+		{OrderStatusProcessing, OrderStatusShipped, true},
+		{OrderStatusProcessing, OrderStatusDelivered, false},
+		{OrderStatusProcessing, OrderStatusCancelled, true},
+		{OrderStatusProcessing, OrderStatusRefunded, true},
+
+		{OrderStatusShipped, OrderStatusPaid, false},
+		{OrderStatusShipped, OrderStatusProcessing, false},
+		{OrderStatusShipped, OrderStatusShipped, false},
+		{OrderStatusShipped, OrderStatusDelivered, true},
+		{OrderStatusShipped, OrderStatusCancelled, false},
+		{OrderStatusShipped, OrderStatusRefunded, true},
+
+		{OrderStatusDelivered, OrderStatusPaid, false},
+		{OrderStatusDelivered, OrderStatusProcessing, false},
+		{OrderStatusDelivered, OrderStatusShipped, false},
+		{OrderStatusDelivered, OrderStatusDelivered, false},
+		{OrderStatusDelivered, OrderStatusCancelled, false},
+		{OrderStatusDelivered, OrderStatusRefunded, true},
+
+		{OrderStatusCancelled, OrderStatusPaid, false},
+		{OrderStatusCancelled, OrderStatusProcessing, false},
+		{OrderStatusCancelled, OrderStatusShipped, false},
+		{OrderStatusCancelled, OrderStatusDelivered, false},
+		{OrderStatusCancelled, OrderStatusCancelled, false},
+		{OrderStatusCancelled, OrderStatusRefunded, false},
+
+		{OrderStatusRefunded, OrderStatusPaid, false},
+		{OrderStatusRefunded, OrderStatusProcessing, false},
+		{OrderStatusRefunded, OrderStatusShipped, false},
+		{OrderStatusRefunded, OrderStatusDelivered, false},
+		{OrderStatusRefunded, OrderStatusCancelled, false},
+		{OrderStatusRefunded, OrderStatusRefunded, false},
+	}
+
+	listed := map[[2]string]bool{}
+	for _, tt := range tests {
+		listed[[2]string{tt.from, tt.to}] = true
+		if got := CanTransitionOrder(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionOrder(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if !listed[[2]string{from, to}] {
+				t.Errorf("pair %s → %s is missing from the table", from, to)
+			}
+		}
+	}
+
+	for _, s := range statuses {
+		if !IsValidOrderStatus(s) {
+			t.Errorf("IsValidOrderStatus(%s) = false", s)
+		}
+		if CanTransitionOrder(s, "lost") || CanTransitionOrder("lost", s) || CanTransitionOrder("", s) {
+			t.Errorf("unknown status accepted in a transition with %s", s)
+		}
+	}
+	if IsValidOrderStatus("lost") || IsValidOrderStatus("") || IsValidOrderStatus("Paid") {
+		t.Error("IsValidOrderStatus accepted an unknown status")
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestSnapshotWeapon(t *testing.T) {
+	item := OrderItem{
+		WeaponID:           3,
+		WeaponName:         "Gauss Rifle",
+		WeaponType:         "kinetic-railgun",
+		WeaponCategoryName: "Kinetic / Railgun",
+		WeaponImage:        "uploads/gauss.png",
+		UnitPrice:          125000,
+	}
+	w := item.SnapshotWeapon()
+	if w.ID != 3 || w.Name != "Gauss Rifle" || w.Price != 125000 || w.ImageURL != "uploads/gauss.png" {
+		t.Errorf("SnapshotWeapon() = %+v", w)
+	}
+	if w.Type != "kinetic-railgun" {
+		t.Errorf("Type = %q, want the category slug", w.Type)
+	}
+	if w.Category == nil || w.Category.Slug != "kinetic-railgun" || w.Category.Names.Get("th") != "Kinetic / Railgun" {
+		t.Errorf("Category = %+v, want the snapshot name under the slug", w.Category)
+	}
+
+	item.WeaponCategoryName = ""
+	if w := item.SnapshotWeapon(); w.Category != nil || w.Type != "kinetic-railgun" {
+		t.Errorf("without a name: Type = %q, Category = %+v", w.Type, w.Category)
+	}
+}
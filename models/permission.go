@@ -0,0 +1,75 @@
+package models
+
+import "gorm.io/gorm"
+
+// Permissions checked by middleware.RequirePermission. Each route declares
+// the permission it needs in routes.SetupRoutes.
+const (
+	PermCatalogWrite   = "catalog:write"   // add and edit weapons and categories
+	PermCatalogArchive = "catalog:archive" // archive, restore and list archived weapons
+	PermOrdersRead     = "orders:read"     // see every customer's orders
+	PermOrdersUpdate   = "orders:update"   // move orders through fulfilment
+	PermOrdersRefund   = "orders:refund"   // cancel or refund an order
+	PermUsersRoles     = "users:roles"     // view roles and change a user's role
+	PermUsersUnlock    = "users:unlock"    // clear a login lockout
+	PermUsersSessions  = "users:sessions"  // see a user's sessions and log them out everywhere
+)
+
+// Role is a named set of permissions. A user has exactly one role, stored by
+// name in users.role. Superuser roles pass every permission check.
+type Role struct {
+	Name        string `json:"name" gorm:"primaryKey"`
+	Description string `json:"description"`
+	IsSuperuser bool   `json:"is_superuser"`
+	// Require2FA makes the role's permissions usable only from a login that
+	// passed a TOTP check.
+	Require2FA  bool         `json:"require_2fa" gorm:"column:require_2fa"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;foreignKey:Name;joinForeignKey:RoleName;references:Code;joinReferences:PermissionCode"`
+}
+
+// Permission is a single capability such as "orders:update".
+type Permission struct {
+	Code        string `json:"code" gorm:"primaryKey"`
+	Description string `json:"description"`
+}
+
+// Can reports whether the role grants perm.
+func (r Role) Can(perm string) bool {
+	if r.IsSuperuser {
+		return true
+	}
+	for _, p := range r.Permissions {
+		if p.Code == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff reports whether the role grants anything beyond a customer account.
+func (r Role) IsStaff() bool {
+	return r.IsSuperuser || len(r.Permissions) > 0
+}
+
+// PermissionCodes lists the codes granted by the role. For superusers that
+// is every permission in the database.
+func (r Role) PermissionCodes(db *gorm.DB) ([]string, error) {
+	codes := []string{}
+	if !r.IsSuperuser {
+		for _, p := range r.Permissions {
+			codes = append(codes, p.Code)
+		}
+		return codes, nil
+	}
+	err := db.Model(&Permission{}).Order("code").Pluck("code", &codes).Error
+	return codes, err
+}
+
+// LoadRole fetches a role and its permissions by name.
+func LoadRole(db *gorm.DB, name string) (Role, error) {
+	var role Role
+	err := db.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("code")
+	}).First(&role, "name = ?", name).Error
+	return role, err
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SigningKey is an asymmetric access token signing key. PrivateKey is
+// encrypted with a key derived from auth.jwt_secret; the id is the "kid"
+// header of the tokens it signs.
+type SigningKey struct {
+	ID          string     `json:"kid" gorm:"primaryKey"`
+	Algorithm   string     `json:"alg" gorm:"not null"`
+	PrivateKey  string     `json:"-" gorm:"not null"`
+	ActivatesAt time.Time  `json:"activates_at" gorm:"not null"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Spec attribute types.
+const (
+	SpecNumber  = "number"  // any number, e.g. weight in kg
+	SpecInteger = "integer" // whole numbers, e.g. ammo capacity
+	SpecText    = "text"    // free text up to 200 characters
+	SpecEnum    = "enum"    // one of Options
+	SpecBoolean = "boolean"
+)
+
+// SpecAttribute is one technical specification weapons of a category can
+// carry, such as range or fire rate. A category's weapons use its own
+// attributes plus those of every parent category.
+type SpecAttribute struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	CategoryID uint          `json:"category_id" gorm:"not null;index"`
+	Key        string        `json:"key" gorm:"not null"`
+	Names      LocalizedText `json:"names" gorm:"type:jsonb;not null"`
+	Type       string        `json:"type" gorm:"not null"`
+	// Unit is shown after the value, e.g. "m" or "rpm".
+	Unit string `json:"unit" gorm:"not null;default:''"`
+	// Options are the allowed values of an enum attribute.
+	Options StringList `json:"options" gorm:"type:jsonb;not null"`
+	// Min and Max bound number and integer values.
+	Min        *float64  `json:"min"`
+	Max        *float64  `json:"max"`
+	Required   bool      `json:"required" gorm:"not null;default:false"`
+	Filterable bool      `json:"filterable" gorm:"not null;default:false"`
+	SortOrder  int       `json:"sort_order" gorm:"not null;default:0"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Specs holds a weapon's specification values by attribute key: numbers,
+// strings or booleans depending on the attribute type. Stored as JSONB.
+type Specs map[string]interface{}
+
+func (s Specs) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]interface{}(s))
+	return string(b), err
+}
+
+func (s *Specs) Scan(src interface{}) error {
+	return scanJSON(src, (*map[string]interface{})(s), "Specs")
+}
+
+// StringList is a list of strings stored as a JSONB array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(src interface{}) error {
+	return scanJSON(src, (*[]string)(l), "StringList")
+}
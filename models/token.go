@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// TokenFamily groups every refresh token issued from one login, and is the
+// "session" users see in GET /api/sessions. Revoking the family logs that
+// login out everywhere: its refresh tokens stop working and AuthMiddleware
+// rejects access tokens that carry its id.
+type TokenFamily struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	// MFA is true when the login passed a second factor. Access tokens from
+	// this family carry it as the "mfa" claim.
+	MFA bool `json:"mfa" gorm:"column:mfa;not null;default:false"`
+	// Where the session was started and last used from.
+	Device     string     `json:"device"`
+	IP         string     `json:"ip" gorm:"column:ip"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// RefreshToken is a single-use, rotating refresh token. Only the SHA-256 hash
+// of the token is stored.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	FamilyID  string    `gorm:"not null;index"`
+	UserID    uint      `gorm:"not null"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Purposes of a UserToken.
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+)
+
+// UserToken is a single-use, expiring token sent to the user by email. Only
+// the SHA-256 hash is stored. Email is the address the token was sent to, so
+// a verification link stops working once the user changes their email.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null"`
+	Email     string    `gorm:"not null"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// LoginChallenge is the short-lived, single-use token Login returns when the
+// account has 2FA enabled. It is exchanged together with a TOTP or recovery
+// code for the real tokens.
+type LoginChallenge struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Top-up intent statuses.
+const (
+	TopupPending   = "pending"
+	TopupSucceeded = "succeeded"
+	TopupFailed    = "failed"
+)
+
+// TopupIntent tracks a top-up from creation until the payment provider
+// confirms it. Credits are only added when it moves to succeeded.
+type TopupIntent struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index;uniqueIndex:idx_topup_user_idempotency,priority:1"`
+	Amount         Money      `json:"amount" gorm:"not null"`
+	Status         string     `json:"status" gorm:"not null;default:pending"`
+	Provider       string     `json:"provider" gorm:"not null;uniqueIndex:idx_topup_provider_ref,priority:1"`
+	ProviderRef    *string    `json:"provider_ref" gorm:"uniqueIndex:idx_topup_provider_ref,priority:2"`
+	ClientSecret   string     `json:"client_secret,omitempty"`
+	RedirectURL    string     `json:"redirect_url,omitempty"`
+	IdempotencyKey *string    `json:"-" gorm:"uniqueIndex:idx_topup_user_idempotency,priority:2"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+}
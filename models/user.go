@@ -1,12 +1,39 @@
 package models
 
+import "time"
+
+// Built-in roles. The full list, including the staff roles, lives in the
+// roles table; see Role.
+const (
+	RoleUser      = "user"
+	RoleSuperuser = "superuser"
+)
+
 type User struct {
-	ID       uint    `gorm:"primaryKey"`
-	Username string  `gorm:"unique" json:"username" binding:"required"`
-	Role     string  `gorm:"default:user" json:"role"`
-	Email    string  `gorm:"unique;not null" json:"email" binding:"required"`
-	Password string  `json:"password" binding:"required"`
-	Credits  float64 `gorm:"default:0" json:"credits"`
-	Address  string  `json:"address" gorm:"type:text"`
-	Avatar   string  `json:"avatar" gorm:"type:text"`
+	ID       uint   `gorm:"primaryKey"`
+	Username string `gorm:"unique" json:"username" binding:"required"`
+	Role     string `gorm:"default:user" json:"role"`
+	Email    string `gorm:"unique;not null" json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Credits  Money  `gorm:"default:0" json:"credits"`
+	Address  string `json:"address" gorm:"type:text"`
+	Avatar   string `json:"avatar" gorm:"type:text"`
+	// Verified is set once the user follows the link sent to Email.
+	Verified   bool       `gorm:"not null;default:false" json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	// TOTP two-factor authentication. TOTPSecret is set when enrollment
+	// starts and TOTPEnabled once the user confirms a code from their app.
+	TOTPSecret   string `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled  bool   `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+}
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the user has
+// lost their authenticator. Only the hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
 }
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WeaponVariant is one purchasable SKU of a weapon, such as the black
+// 200-charge model. Every weapon has at least one; weapons without real
+// variants have a single default one with no options.
+//
+// Price overrides the weapon's price when set. weapons.stock is kept equal to
+// the sum of the live variants' stock by a database trigger, so stock is only
+// ever changed here.
+type WeaponVariant struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	WeaponID   uint           `gorm:"not null;index" json:"weapon_id"`
+	SKU        string         `gorm:"column:sku;not null;uniqueIndex" json:"sku"`
+	Options    VariantOptions `gorm:"type:jsonb;not null" json:"options"`
+	Price      *Money         `json:"price"`
+	Stock      int            `gorm:"not null;default:0" json:"stock"`
+	ArchivedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+
+	// UnitPrice is what the variant sells for; filled by handlers.
+	UnitPrice Money `gorm:"-" json:"unit_price"`
+}
+
+// PriceFor returns the variant's price, falling back to the weapon's.
+func (v WeaponVariant) PriceFor(w Weapon) Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return w.Price
+}
+
+// VariantOptions are the attributes that tell a weapon's variants apart,
+// e.g. {"color": "black", "capacity": "200"}. Stored as a JSONB object.
+type VariantOptions map[string]string
+
+// Names returns the option names in alphabetical order.
+func (o VariantOptions) Names() []string {
+	names := make([]string, 0, len(o))
+	for k := range o {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	return jsonMapValue(o)
+}
+
+func (o *VariantOptions) Scan(src interface{}) error {
+	return scanJSON(src, (*map[string]string)(o), "VariantOptions")
+}
@@ -1,12 +1,24 @@
 package models
 
+import "gorm.io/gorm"
+
+// Weapon is soft-deleted through ArchivedAt: archived weapons disappear from
+// normal queries but their rows stay so existing orders still resolve.
+//
+// Type is the slug of the weapon's Category. Price is the price of variants
+// that don't set their own, and Stock is the total of the variants' stock.
+// Specs follow the SpecAttributes of the category and its parents.
 type Weapon struct {
-	ID          uint    `gorm:"primaryKey" json:"id"`
-	Name        string  `json:"name" binding:"required"`
-	Type        string  `json:"type" binding:"required"`
-	PowerLevel  int     `json:"power_level"`
-	Price       float64 `json:"price" binding:"required"`
-	Description string  `json:"description"`
-	Stock       int     `json:"stock"`
-	ImageURL    string  `json:"image_url"`
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Name        string          `json:"name" binding:"required"`
+	Type        string          `json:"type" binding:"required"`
+	Category    *Category       `gorm:"foreignKey:Type;references:Slug" json:"category,omitempty"`
+	Variants    []WeaponVariant `gorm:"foreignKey:WeaponID" json:"variants,omitempty"`
+	PowerLevel  int             `json:"power_level"`
+	Price       Money           `json:"price" binding:"required"`
+	Description string          `json:"description"`
+	Stock       int             `json:"stock"`
+	ImageURL    string          `json:"image_url"`
+	Specs       Specs           `gorm:"type:jsonb;not null;default:'{}'" json:"specs"`
+	ArchivedAt  gorm.DeletedAt  `gorm:"index" json:"archived_at"`
 }
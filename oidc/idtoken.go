@@ -0,0 +1,218 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// allowedAlgs are the asymmetric algorithms accepted for ID tokens. HMAC and
+// "none" are never accepted.
+var allowedAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// keyRefetchInterval limits how often an unknown kid triggers a JWKS fetch.
+const keyRefetchInterval = time.Minute
+
+// IDClaims are the ID token claims the login flow uses.
+type IDClaims struct {
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts true/false as JSON booleans or strings; some providers
+// send email_verified as "true".
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// raw and returns its claims.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDClaims, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims IDClaims
+	token, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, m.JWKSURI, kid)
+	},
+		jwt.WithValidMethods(allowedAlgs),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	// With several audiences the token must name us as the authorized party.
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp does not match client", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+// key returns the verification key for kid, refetching the key set when the
+// kid is unknown (the provider may have rotated keys).
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := p.keys == nil || time.Since(p.keysAt) > metadataTTL
+	if _, ok := p.keys[kid]; !ok && !stale && time.Since(p.keysSeen) > keyRefetchInterval {
+		stale = true
+		p.keysSeen = time.Now()
+	}
+	if stale {
+		var set jwkSet
+		if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+			return nil, fmt.Errorf("oidc: fetch keys for %s: %w", p.Name, err)
+		}
+		p.keys, p.keysAt = set.parse(), time.Now()
+	}
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	// Providers with a single key sometimes omit kid.
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc: no key with kid %q", kid)
+}
+
+// jwk is one entry of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// parse converts the signing keys it understands and skips the rest.
+func (s jwkSet) parse() map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			out[k.Kid] = pub
+		}
+	}
+	return out
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64Int(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64Int(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || n.BitLen() < 2048 {
+			return nil, fmt.Errorf("oidc: weak RSA key %q", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("oidc: unsupported curve %q", k.Crv)
+		}
+		x, err := b64Int(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64Int(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("oidc: EC key %q is not on its curve", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("oidc: unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("oidc: bad Ed25519 key %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("oidc: unsupported key type %q", k.Kty)
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("oidc: bad key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// MarshalJWK is used by the mock provider to publish its key.
+func MarshalJWK(kid string, pub *rsa.PublicKey) json.RawMessage {
+	raw, _ := json.Marshal(jwkSet{Keys: []jwk{{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+	return raw
+}
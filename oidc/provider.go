@@ -0,0 +1,252 @@
+// Package oidc implements the relying-party side of OpenID Connect: provider
+// discovery, the authorization code flow with PKCE and ID token verification
+// against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bannawat01/ec-space/config"
+)
+
+var (
+	// ErrUnknownProvider is returned by Get for unconfigured provider names.
+	ErrUnknownProvider = errors.New("oidc: unknown provider")
+	// ErrInvalidIDToken is returned for any ID token that fails verification.
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+)
+
+// metadataTTL is how long discovery documents and key sets are cached.
+const metadataTTL = time.Hour
+
+// Metadata is the subset of the discovery document the flow needs.
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Provider is one configured identity provider.
+type Provider struct {
+	Name string
+	cfg  config.OIDCProviderConfig
+	http *http.Client
+
+	mu       sync.Mutex
+	meta     *Metadata
+	metaAt   time.Time
+	keys     map[string]interface{}
+	keysAt   time.Time
+	keysSeen time.Time // last refetch triggered by an unknown kid
+}
+
+// NewProvider returns a Provider for cfg. Nothing is fetched until first use.
+func NewProvider(name string, cfg config.OIDCProviderConfig) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		Name: name,
+		cfg:  cfg,
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// DisplayName is the label for the login button.
+func (p *Provider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.Name
+}
+
+// Metadata returns the provider's discovery document, fetching it if the
+// cached copy is missing or stale.
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && time.Since(p.metaAt) < metadataTTL {
+		return p.meta, nil
+	}
+
+	wellKnown := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var m Metadata
+	if err := p.getJSON(ctx, wellKnown, &m); err != nil {
+		return nil, fmt.Errorf("oidc: discovery for %s: %w", p.Name, err)
+	}
+	// The document must describe the issuer we were configured with,
+	// otherwise tokens from a different issuer could be accepted.
+	if strings.TrimRight(m.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc: discovery for %s: issuer %q does not match %q", p.Name, m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery for %s: missing endpoints", p.Name)
+	}
+	p.meta, p.metaAt = &m, time.Now()
+	return p.meta, nil
+}
+
+// AuthCodeURL returns the URL to send the browser to. state and nonce are
+// checked again on the way back; challenge is the PKCE S256 challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: bad authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// tokenResponse is the token endpoint's answer.
+type tokenResponse struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// Exchange trades an authorization code and PKCE verifier for tokens and
+// returns the raw ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return "", fmt.Errorf("oidc: token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return "", fmt.Errorf("oidc: token endpoint returned %d %s %s", resp.StatusCode, tr.Error, tr.ErrorDesc)
+	}
+	if tr.IDToken == "" {
+		return "", errors.New("oidc: token response has no id_token")
+	}
+	return tr.IDToken, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+// NewPKCE returns a random code verifier and its S256 challenge.
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString returns n random bytes encoded as URL-safe base64.
+func RandomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]*Provider{}
+)
+
+// Configure replaces the registered providers with those in cfg.
+func Configure(cfg map[string]config.OIDCProviderConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers = make(map[string]*Provider, len(cfg))
+	for name, c := range cfg {
+		providers[name] = NewProvider(name, c)
+	}
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (*Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// List returns every registered provider ordered by name.
+func List() []*Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]*Provider, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,109 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a fake webhook body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider is an in-memory provider for development and tests.
+//
+// With AutoConfirm set, every payment reports succeeded as soon as it is
+// checked, which lets the frontend run the whole flow without a real gateway.
+// Otherwise payments stay pending until Complete/Fail is called or a signed
+// webhook arrives.
+type FakeProvider struct {
+	AutoConfirm bool
+
+	secret   []byte
+	mu       sync.Mutex
+	payments map[string]Status
+}
+
+// NewFakeProvider returns a FakeProvider that signs webhooks with secret.
+func NewFakeProvider(secret string, autoConfirm bool) *FakeProvider {
+	return &FakeProvider{
+		AutoConfirm: autoConfirm,
+		secret:      []byte(secret),
+		payments:    map[string]Status{},
+	}
+}
+
+func (f *FakeProvider) Name() string { return "fake" }
+
+func (f *FakeProvider) CreateIntent(_ context.Context, req IntentRequest) (Intent, error) {
+	if req.Amount <= 0 {
+		return Intent{}, fmt.Errorf("payments: amount must be positive")
+	}
+	ref := "fake_" + uuid.NewString()
+	f.mu.Lock()
+	f.payments[ref] = StatusPending
+	f.mu.Unlock()
+	return Intent{Reference: ref, ClientSecret: ref + "_secret"}, nil
+}
+
+func (f *FakeProvider) FetchStatus(_ context.Context, reference string) (Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st, ok := f.payments[reference]
+	if !ok {
+		return "", ErrUnknownReference
+	}
+	if st == StatusPending && f.AutoConfirm {
+		st = StatusSucceeded
+		f.payments[reference] = st
+	}
+	return st, nil
+}
+
+// Complete marks a fake payment as paid.
+func (f *FakeProvider) Complete(reference string) { f.set(reference, StatusSucceeded) }
+
+// Fail marks a fake payment as failed.
+func (f *FakeProvider) Fail(reference string) { f.set(reference, StatusFailed) }
+
+func (f *FakeProvider) set(reference string, st Status) {
+	f.mu.Lock()
+	f.payments[reference] = st
+	f.mu.Unlock()
+}
+
+// Sign returns the signature the fake provider expects for body.
+func (f *FakeProvider) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *FakeProvider) ParseWebhook(r *http.Request) (Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return Event{}, err
+	}
+	got, err := hex.DecodeString(r.Header.Get(FakeSignatureHeader))
+	if err != nil {
+		return Event{}, ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(f.Sign(body))
+	if !hmac.Equal(got, want) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return Event{}, fmt.Errorf("payments: decode webhook: %w", err)
+	}
+	f.set(ev.Reference, ev.Status)
+	return ev, nil
+}
@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFakeProviderWebhookSignature(t *testing.T) {
+	f := NewFakeProvider("whsec", false)
+	intent, err := f.CreateIntent(context.Background(), IntentRequest{IntentID: 1, UserID: 2, Amount: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"reference":"` + intent.Reference + `","status":"succeeded","amount":5000}`
+	other := NewFakeProvider("another secret", false)
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		wantErr   error
+	}{
+		{"valid", body, f.Sign([]byte(body)), nil},
+		{"missing signature", body, "", ErrInvalidSignature},
+		{"not hex", body, "zz" + f.Sign([]byte(body))[2:], ErrInvalidSignature},
+		{"other secret", body, other.Sign([]byte(body)), ErrInvalidSignature},
+		{"tampered body", strings.Replace(body, "5000", "500000", 1), f.Sign([]byte(body)), ErrInvalidSignature},
+		{"truncated signature", body, f.Sign([]byte(body))[:32], ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/payments/webhook/fake", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				r.Header.Set(FakeSignatureHeader, tt.signature)
+			}
+			ev, err := f.ParseWebhook(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseWebhook() err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (ev.Reference != intent.Reference || ev.Status != StatusSucceeded || ev.Amount != 5000) {
+				t.Errorf("ParseWebhook() = %+v", ev)
+			}
+		})
+	}
+
+	if st, err := f.FetchStatus(context.Background(), intent.Reference); err != nil || st != StatusSucceeded {
+		t.Errorf("status after the valid webhook = %s, %v; want succeeded", st, err)
+	}
+}
+
+func TestFakeProviderStatus(t *testing.T) {
+	ctx := context.Background()
+	manual := NewFakeProvider("whsec", false)
+	a, _ := manual.CreateIntent(ctx, IntentRequest{Amount: 100})
+	if st, _ := manual.FetchStatus(ctx, a.Reference); st != StatusPending {
+		t.Errorf("new payment = %s, want pending", st)
+	}
+	manual.Fail(a.Reference)
+	if st, _ := manual.FetchStatus(ctx, a.Reference); st != StatusFailed {
+		t.Errorf("failed payment = %s, want failed", st)
+	}
+
+	auto := NewFakeProvider("whsec", true)
+	b, _ := auto.CreateIntent(ctx, IntentRequest{Amount: 100})
+	if st, _ := auto.FetchStatus(ctx, b.Reference); st != StatusSucceeded {
+		t.Errorf("auto-confirmed payment = %s, want succeeded", st)
+	}
+	if _, err := auto.FetchStatus(ctx, "fake_unknown"); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("unknown reference: err = %v", err)
+	}
+	if _, err := auto.CreateIntent(ctx, IntentRequest{Amount: 0}); err == nil {
+		t.Error("CreateIntent accepted a zero amount")
+	}
+}
@@ -0,0 +1,110 @@
+// Package payments defines the PaymentProvider abstraction used by the top-up
+// flow. Credits are only added once a provider reports a payment as succeeded,
+// either through a signed webhook or an explicit status check.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Status is the state of a payment as reported by a provider.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+var (
+	// ErrInvalidSignature is returned when a webhook signature does not verify.
+	ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+	// ErrUnknownReference is returned when a provider has no payment with the given reference.
+	ErrUnknownReference = errors.New("payments: unknown payment reference")
+	// ErrUnknownProvider is returned by Get for unregistered provider names.
+	ErrUnknownProvider = errors.New("payments: unknown provider")
+)
+
+// IntentRequest describes a top-up the customer wants to pay for.
+// Amount is in minor units (see models.Money).
+type IntentRequest struct {
+	IntentID uint
+	UserID   uint
+	Amount   int64
+}
+
+// Intent is the provider-side payment created for an IntentRequest.
+type Intent struct {
+	Reference    string `json:"reference"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RedirectURL  string `json:"redirect_url,omitempty"`
+}
+
+// Event is a verified webhook notification about a payment.
+type Event struct {
+	Reference string `json:"reference"`
+	Status    Status `json:"status"`
+	Amount    int64  `json:"amount"`
+}
+
+// Provider is implemented by every payment backend.
+type Provider interface {
+	// Name identifies the provider in URLs and stored intents.
+	Name() string
+	// CreateIntent starts a payment with the provider.
+	CreateIntent(ctx context.Context, req IntentRequest) (Intent, error)
+	// FetchStatus asks the provider for the current state of a payment.
+	FetchStatus(ctx context.Context, reference string) (Status, error)
+	// ParseWebhook verifies the signature of a callback and decodes it.
+	ParseWebhook(r *http.Request) (Event, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+	fallback  string
+)
+
+// Register makes p available under p.Name(). The first provider registered
+// becomes the default one.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+	if fallback == "" {
+		fallback = p.Name()
+	}
+}
+
+// SetDefault selects the provider used for new top-up intents.
+func SetDefault(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := providers[name]; !ok {
+		return ErrUnknownProvider
+	}
+	fallback = name
+	return nil
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Default returns the provider used for new top-up intents.
+func Default() (Provider, error) {
+	mu.RLock()
+	name := fallback
+	mu.RUnlock()
+	return Get(name)
+}
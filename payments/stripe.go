@@ -0,0 +1,206 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// StripeSignatureHeader carries the timestamp and signatures of a Stripe webhook.
+	StripeSignatureHeader = "Stripe-Signature"
+	// stripeTolerance is how old a webhook timestamp may be, against replays.
+	stripeTolerance = 5 * time.Minute
+)
+
+// StripeProvider takes top-up payments through Stripe PaymentIntents. The
+// client secret it returns is used by Stripe.js on the frontend; Stripe then
+// reports the result to /api/payments/webhook/stripe.
+type StripeProvider struct {
+	// BaseURL defaults to https://api.stripe.com.
+	BaseURL string
+	Client  *http.Client
+
+	secretKey     string
+	webhookSecret string
+	currency      string
+}
+
+// NewStripeProvider returns a provider that charges in currency (an ISO code
+// with two decimal places, such as "thb") and verifies webhooks with the
+// endpoint's signing secret.
+func NewStripeProvider(secretKey, webhookSecret, currency string) *StripeProvider {
+	return &StripeProvider{
+		BaseURL:       "https://api.stripe.com",
+		Client:        &http.Client{Timeout: 15 * time.Second},
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		currency:      strings.ToLower(currency),
+	}
+}
+
+func (s *StripeProvider) Name() string { return "stripe" }
+
+// stripeIntent is the part of a Stripe PaymentIntent the provider reads.
+type stripeIntent struct {
+	ID           string `json:"id"`
+	Object       string `json:"object"`
+	Amount       int64  `json:"amount"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// status maps a PaymentIntent status. Intents whose payment attempt failed go
+// back to requires_payment_method and can still be paid, so only canceled
+// ones count as failed.
+func (pi stripeIntent) status() Status {
+	switch pi.Status {
+	case "succeeded":
+		return StatusSucceeded
+	case "canceled":
+		return StatusFailed
+	}
+	return StatusPending
+}
+
+func (s *StripeProvider) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	if req.Amount <= 0 {
+		return Intent{}, fmt.Errorf("payments: amount must be positive")
+	}
+	form := url.Values{
+		"amount":                             {strconv.FormatInt(req.Amount, 10)},
+		"currency":                           {s.currency},
+		"automatic_payment_methods[enabled]": {"true"},
+		"metadata[topup_intent_id]":          {strconv.FormatUint(uint64(req.IntentID), 10)},
+		"metadata[user_id]":                  {strconv.FormatUint(uint64(req.UserID), 10)},
+	}
+	var pi stripeIntent
+	// The idempotency key makes a retried request return the same payment.
+	key := "topup-" + strconv.FormatUint(uint64(req.IntentID), 10)
+	if err := s.do(ctx, http.MethodPost, "/v1/payment_intents", form, key, &pi); err != nil {
+		return Intent{}, err
+	}
+	return Intent{Reference: pi.ID, ClientSecret: pi.ClientSecret}, nil
+}
+
+func (s *StripeProvider) FetchStatus(ctx context.Context, reference string) (Status, error) {
+	var pi stripeIntent
+	if err := s.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(reference), nil, "", &pi); err != nil {
+		return "", err
+	}
+	return pi.status(), nil
+}
+
+// do calls the Stripe API and decodes the response into out.
+func (s *StripeProvider) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("payments: stripe request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("payments: stripe response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrUnknownReference
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(raw, &e)
+		return fmt.Errorf("payments: stripe returned %d: %s", resp.StatusCode, e.Error.Message)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("payments: decode stripe response: %w", err)
+	}
+	return nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header ("t=<unix>,v1=<hex>",
+// an HMAC-SHA256 of "<t>.<body>") and decodes payment_intent events. Other
+// event types should not be enabled on the webhook endpoint.
+func (s *StripeProvider) ParseWebhook(r *http.Request) (Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return Event{}, err
+	}
+
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(r.Header.Get(StripeSignatureHeader), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return Event{}, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(t, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return Event{}, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	want := mac.Sum(nil)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal(sig, want) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var ev struct {
+		Type string `json:"type"`
+		Data struct {
+			Object stripeIntent `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return Event{}, fmt.Errorf("payments: decode webhook: %w", err)
+	}
+	pi := ev.Data.Object
+	if pi.Object != "payment_intent" || pi.ID == "" {
+		return Event{}, fmt.Errorf("payments: unexpected stripe event %q", ev.Type)
+	}
+	return Event{Reference: pi.ID, Status: pi.status(), Amount: pi.Amount}, nil
+}
@@ -1,49 +1,112 @@
 package routes
 
 import (
+	"github.com/Bannawat01/ec-space/config"
 	"github.com/Bannawat01/ec-space/handlers"
 	"github.com/Bannawat01/ec-space/middleware"
+	"github.com/Bannawat01/ec-space/models"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(r *gin.Engine) {
 	// Public routes
+	r.GET("/.well-known/jwks.json", handlers.GetJWKS)
 	r.GET("/api/weapons", handlers.GetWeapons)
+	r.GET("/api/weapons/suggest", handlers.SuggestWeapons)
 	r.GET("/api/weapons/:id", handlers.GetWeapon)
+	r.GET("/api/categories", handlers.GetCategories)
+	r.GET("/api/categories/:id/specs", handlers.GetCategorySpecs)
 	r.POST("/api/register", handlers.Register)
 	r.POST("/api/login", handlers.Login)
+	r.POST("/api/login/2fa", handlers.LoginTwoFactor)
+	r.POST("/api/token/refresh", handlers.RefreshToken)
+	r.POST("/api/password/forgot", handlers.ForgotPassword)
+	r.POST("/api/password/reset", handlers.ResetPassword)
+	r.POST("/api/email/verify", handlers.VerifyEmail)
+	r.GET("/api/oidc/providers", handlers.GetOIDCProviders)
+	r.GET("/api/oidc/:provider/login", handlers.OIDCLogin)
+	r.GET("/api/oidc/:provider/callback", handlers.OIDCCallback)
+	r.POST("/api/payments/webhook/:provider", handlers.PaymentWebhook)
 
-	// Authenticated routes
+	// Authenticated routes — a login (JWT) or an API key
 	auth := r.Group("/api")
-	auth.Use(middleware.AuthMiddleware(handlers.GetJWTKey()))
+	auth.Use(middleware.AuthMiddleware(handlers.JWTKeys()))
 	{
+		// Managing the login itself needs a real login; API keys are refused
+		session := auth.Group("", middleware.SessionOnly())
+		session.POST("/logout", handlers.Logout)
+		session.POST("/email/verify/resend", handlers.ResendVerification)
+		// Changing the email would let a key take over the account via password reset
+		session.PATCH("/profile", handlers.UpdateProfile)
+
+		// Two-factor authentication
+		session.GET("/2fa", handlers.GetTwoFactorStatus)
+		session.POST("/2fa/enroll", handlers.EnrollTwoFactor)
+		session.POST("/2fa/confirm", handlers.ConfirmTwoFactor)
+		session.POST("/2fa/disable", handlers.DisableTwoFactor)
+		session.POST("/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+
+		// Sessions (logged-in devices)
+		session.GET("/sessions", handlers.GetSessions)
+		session.DELETE("/sessions", handlers.RevokeOtherSessions)
+		session.DELETE("/sessions/:id", handlers.RevokeSession)
+
+		// API keys
+		session.GET("/api-keys", handlers.GetAPIKeys)
+		session.GET("/api-keys/scopes", handlers.GetAPIKeyScopes)
+		session.POST("/api-keys", handlers.CreateAPIKey)
+		session.DELETE("/api-keys/:id", handlers.RevokeAPIKey)
+
+		// Customer routes — API keys need account:read (GET) or account:write
+		account := auth.Group("", middleware.AccountScope())
+
 		// Profile & Topup
-		auth.GET("/profile", handlers.GetProfile)
-		// Allow users to update their profile (address, email)
-		auth.PATCH("/profile", handlers.UpdateProfile)
-		auth.POST("/topup", handlers.Topup)
+		account.GET("/profile", handlers.GetProfile)
+		account.POST("/topup/intents", handlers.CreateTopupIntent)
+		account.GET("/topup/intents/:id", handlers.GetTopupIntent)
+		account.POST("/topup/intents/:id/confirm", handlers.ConfirmTopupIntent)
+		account.GET("/wallet/transactions", handlers.GetWalletTransactions)
 
 		// Cart
-		auth.GET("/cart", handlers.GetCart)
-		auth.POST("/cart", handlers.AddToCart)
-		auth.DELETE("/cart/:weapon_id", handlers.RemoveFromCart)
+		account.GET("/cart", handlers.GetCart)
+		account.POST("/cart", handlers.AddToCart)
+		account.DELETE("/cart/:variant_id", handlers.RemoveFromCart)
 
 		// Orders
-		auth.GET("/orders", handlers.GetOrders)
-		auth.POST("/orders", handlers.CreateOrder)
+		account.GET("/orders", handlers.GetOrders)
+		account.POST("/orders", handlers.CreateOrder)
+		account.POST("/orders/:id/cancel", handlers.CancelOrder)
 	}
 
-	// Admin routes
+	// Admin routes — each route declares the permission it needs (API keys also need it as a scope)
 	admin := r.Group("/api/admin")
-	admin.Use(middleware.AuthMiddleware(handlers.GetJWTKey()))
-	admin.Use(middleware.AdminMiddleware(handlers.GetJWTKey()))
+	admin.Use(middleware.AuthMiddleware(handlers.JWTKeys()))
 	{
-		admin.POST("/weapons", handlers.AddWeapon)
-		admin.PATCH("/weapons/:id", handlers.UpdateWeapon)
-		admin.DELETE("/weapons/:id", handlers.DeleteWeapon)
-		admin.GET("/orders", handlers.GetAllOrders)
+		admin.POST("/weapons", middleware.RequirePermission(models.PermCatalogWrite), handlers.AddWeapon)
+		admin.PATCH("/weapons/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.UpdateWeapon)
+		admin.DELETE("/weapons/:id", middleware.RequirePermission(models.PermCatalogArchive), handlers.DeleteWeapon)
+		admin.GET("/weapons/archived", middleware.RequirePermission(models.PermCatalogArchive), handlers.GetArchivedWeapons)
+		admin.POST("/weapons/:id/restore", middleware.RequirePermission(models.PermCatalogArchive), handlers.RestoreWeapon)
+		admin.POST("/weapons/:id/variants", middleware.RequirePermission(models.PermCatalogWrite), handlers.CreateVariant)
+		admin.PATCH("/variants/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.UpdateVariant)
+		admin.DELETE("/variants/:id", middleware.RequirePermission(models.PermCatalogArchive), handlers.DeleteVariant)
+		admin.POST("/categories", middleware.RequirePermission(models.PermCatalogWrite), handlers.CreateCategory)
+		admin.PATCH("/categories/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.UpdateCategory)
+		admin.DELETE("/categories/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.DeleteCategory)
+		admin.POST("/categories/:id/specs", middleware.RequirePermission(models.PermCatalogWrite), handlers.CreateSpecAttribute)
+		admin.PATCH("/specs/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.UpdateSpecAttribute)
+		admin.DELETE("/specs/:id", middleware.RequirePermission(models.PermCatalogWrite), handlers.DeleteSpecAttribute)
+		admin.GET("/orders", middleware.RequirePermission(models.PermOrdersRead), handlers.GetAllOrders)
+		// Cancelling or refunding additionally needs orders:refund (checked in the handler)
+		admin.PATCH("/orders/:id/status", middleware.RequirePermission(models.PermOrdersUpdate), handlers.UpdateOrderStatus)
+		admin.GET("/roles", middleware.RequirePermission(models.PermUsersRoles), handlers.GetRoles)
+		admin.PATCH("/roles/:name/2fa", middleware.RequirePermission(models.PermUsersRoles), handlers.SetRoleTwoFactor)
+		admin.PATCH("/users/:id/role", middleware.RequirePermission(models.PermUsersRoles), handlers.UpdateUserRole)
+		admin.POST("/users/:id/unlock", middleware.RequirePermission(models.PermUsersUnlock), handlers.UnlockUser)
+		admin.GET("/users/:id/sessions", middleware.RequirePermission(models.PermUsersSessions), handlers.GetUserSessions)
+		admin.POST("/users/:id/logout", middleware.RequirePermission(models.PermUsersSessions), handlers.ForceLogoutUser)
 	}
 
 	// Static files
-	r.Static("/uploads", "./uploads")
+	r.Static("/uploads", config.App.Server.UploadDir)
 }
@@ -0,0 +1,60 @@
+package utils
+
+import "strings"
+
+// DeviceName turns a User-Agent header into a short label such as
+// "Chrome on Windows" for the session list. Unknown agents fall back to
+// "Unknown device"; this is for display only, never for security decisions.
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"samsungbrowser/", "Samsung Internet"},
+		{"firefox/", "Firefox"},
+		{"fxios/", "Firefox"},
+		{"crios/", "Chrome"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+		{"python-requests/", "Python"},
+		{"go-http-client/", "Go"},
+		{"postmanruntime/", "Postman"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}